@@ -0,0 +1,146 @@
+// Package cache provides an optional, local bbolt-backed cache of
+// ReplicaClient listings (Generations, Snapshots, WALSegments) so that
+// `litestream restore` and `litestream snapshots` don't repeatedly pay LIST
+// latency against buckets with thousands of segments per generation.
+package cache
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// entry is the serialized form of a single cached listing.
+type entry struct {
+	Fingerprint string          `json:"fingerprint"`
+	CachedAt    time.Time       `json:"cachedAt"`
+	Payload     json.RawMessage `json:"payload"`
+}
+
+// Store is a bbolt database holding cached listings for many replicas,
+// scoped by a hash of each replica's URL so one cache file can serve an
+// entire litestream process, similar to how rclone's lib/kv multiplexes
+// backends into a single db file.
+type Store struct {
+	db *bolt.DB
+}
+
+// Open opens (creating if necessary) a Store at path.
+func Open(path string) (*Store, error) {
+	db, err := bolt.Open(path, 0o600, &bolt.Options{Timeout: 5 * time.Second})
+	if err != nil {
+		return nil, fmt.Errorf("open cache %s: %w", path, err)
+	}
+	return &Store{db: db}, nil
+}
+
+// Close releases the underlying bbolt database.
+func (s *Store) Close() error {
+	return s.db.Close()
+}
+
+// replicaBucket returns the bbolt bucket name scoping url's entries.
+func replicaBucket(url string) []byte {
+	sum := sha256.Sum256([]byte(url))
+	return []byte(hex.EncodeToString(sum[:8]))
+}
+
+// get looks up key within url's bucket and decodes it into v. It reports
+// false if no entry is present, or if fingerprint no longer matches what's
+// cached (e.g. an ETag/last-modified check failed upstream).
+func (s *Store) get(url, key, fingerprint string, v any) (bool, error) {
+	var found bool
+	err := s.db.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket(replicaBucket(url))
+		if b == nil {
+			return nil
+		}
+		data := b.Get([]byte(key))
+		if data == nil {
+			return nil
+		}
+
+		var e entry
+		if err := json.Unmarshal(data, &e); err != nil {
+			return fmt.Errorf("unmarshal cache entry: %w", err)
+		}
+		if e.Fingerprint != fingerprint {
+			return nil
+		}
+		if err := json.Unmarshal(e.Payload, v); err != nil {
+			return fmt.Errorf("unmarshal cache payload: %w", err)
+		}
+		found = true
+		return nil
+	})
+	return found, err
+}
+
+// put stores v under key within url's bucket, tagged with fingerprint.
+func (s *Store) put(url, key, fingerprint string, v any) error {
+	payload, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("marshal cache payload: %w", err)
+	}
+	data, err := json.Marshal(entry{Fingerprint: fingerprint, CachedAt: time.Now(), Payload: payload})
+	if err != nil {
+		return err
+	}
+
+	return s.db.Update(func(tx *bolt.Tx) error {
+		b, err := tx.CreateBucketIfNotExists(replicaBucket(url))
+		if err != nil {
+			return err
+		}
+		return b.Put([]byte(key), data)
+	})
+}
+
+// invalidate removes every cached entry for url whose key has prefix. An
+// empty prefix clears every entry for url.
+func (s *Store) invalidate(url, prefix string) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(replicaBucket(url))
+		if b == nil {
+			return nil
+		}
+
+		var keys [][]byte
+		c := b.Cursor()
+		for k, _ := c.First(); k != nil; k, _ = c.Next() {
+			if prefix == "" || (len(k) >= len(prefix) && string(k[:len(prefix)]) == prefix) {
+				keys = append(keys, append([]byte(nil), k...))
+			}
+		}
+		for _, k := range keys {
+			if err := b.Delete(k); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// Purge removes every cached entry for every replica. Used by the
+// "litestream cache purge" CLI command.
+func (s *Store) Purge() error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		var names [][]byte
+		if err := tx.ForEach(func(name []byte, _ *bolt.Bucket) error {
+			names = append(names, append([]byte(nil), name...))
+			return nil
+		}); err != nil {
+			return err
+		}
+		for _, name := range names {
+			if err := tx.DeleteBucket(name); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
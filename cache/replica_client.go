@@ -0,0 +1,296 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/benbjohnson/litestream"
+)
+
+// ReplicaClient wraps an underlying litestream.ReplicaClient, memoizing
+// Generations, Snapshots and WALSegments listings in a local Store. It is
+// installed in place of Replica.Client by WrapReplica, which the CLI calls
+// when the config's cache-path is set.
+//
+// Writes pass straight through to the underlying client; whichever
+// generation they touch is invalidated afterward so a process that both
+// replicates and restores stays consistent.
+type ReplicaClient struct {
+	litestream.ReplicaClient // underlying client; methods not overridden below pass straight through
+
+	url   string
+	store *Store
+}
+
+// Wrap returns client wrapped with a cache backed by store. url identifies
+// the replica for cache scoping (its configured URL works well) and need
+// not be a literal network address.
+func Wrap(client litestream.ReplicaClient, url string, store *Store) *ReplicaClient {
+	return &ReplicaClient{ReplicaClient: client, url: url, store: store}
+}
+
+// WrapReplica opens a Store at cachePath and wraps r.Client with it, scoped
+// by r.Name(). Callers are responsible for closing the returned Store when
+// the replica is torn down.
+func WrapReplica(r *litestream.Replica, cachePath string) (*Store, error) {
+	store, err := Open(cachePath)
+	if err != nil {
+		return nil, err
+	}
+	r.Client = Wrap(r.Client, r.Name(), store)
+	return store, nil
+}
+
+// fingerprint returns the underlying client's current fingerprint for
+// generation (or, if generation is empty, for the top-level generation
+// list), so get/put can detect a listing that changed out from under this
+// cache without an explicit invalidation call. Clients that don't
+// implement litestream.Fingerprinter always fingerprint as "", meaning
+// they're cached until explicitly invalidated.
+func (c *ReplicaClient) fingerprint(ctx context.Context, generation string) (string, error) {
+	fp, ok := c.ReplicaClient.(litestream.Fingerprinter)
+	if !ok {
+		return "", nil
+	}
+	if generation == "" {
+		return fp.GenerationsFingerprint(ctx)
+	}
+	return fp.GenerationFingerprint(ctx, generation)
+}
+
+func (c *ReplicaClient) Generations(ctx context.Context) ([]string, error) {
+	const key = "generations"
+
+	fingerprint, err := c.fingerprint(ctx, "")
+	if err != nil {
+		return nil, fmt.Errorf("fingerprint: %w", err)
+	}
+
+	var generations []string
+	if ok, err := c.store.get(c.url, key, fingerprint, &generations); err != nil {
+		return nil, err
+	} else if ok {
+		return generations, nil
+	}
+
+	generations, err = c.ReplicaClient.Generations(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err := c.store.put(c.url, key, fingerprint, generations); err != nil {
+		return nil, fmt.Errorf("cache generations: %w", err)
+	}
+	return generations, nil
+}
+
+func (c *ReplicaClient) Snapshots(ctx context.Context, generation string) ([]litestream.SnapshotInfo, error) {
+	key := "snapshots:" + generation
+
+	fingerprint, err := c.fingerprint(ctx, generation)
+	if err != nil {
+		return nil, fmt.Errorf("fingerprint: %w", err)
+	}
+
+	var infos []litestream.SnapshotInfo
+	if ok, err := c.store.get(c.url, key, fingerprint, &infos); err != nil {
+		return nil, err
+	} else if ok {
+		return infos, nil
+	}
+
+	infos, err = c.ReplicaClient.Snapshots(ctx, generation)
+	if err != nil {
+		return nil, err
+	}
+	if err := c.store.put(c.url, key, fingerprint, infos); err != nil {
+		return nil, fmt.Errorf("cache snapshots: %w", err)
+	}
+	return infos, nil
+}
+
+func (c *ReplicaClient) WALSegments(ctx context.Context, generation string) (litestream.WALSegmentIterator, error) {
+	key := "wal:" + generation
+
+	fingerprint, err := c.fingerprint(ctx, generation)
+	if err != nil {
+		return nil, fmt.Errorf("fingerprint: %w", err)
+	}
+
+	var infos []litestream.WALSegmentInfo
+	if ok, err := c.store.get(c.url, key, fingerprint, &infos); err != nil {
+		return nil, err
+	} else if ok {
+		return litestream.NewWALSegmentInfoSliceIterator(infos), nil
+	}
+
+	itr, err := c.ReplicaClient.WALSegments(ctx, generation)
+	if err != nil {
+		return nil, err
+	}
+	for itr.Next() {
+		infos = append(infos, itr.WALSegment())
+	}
+	if err := itr.Close(); err != nil {
+		return nil, err
+	}
+	if err := c.store.put(c.url, key, fingerprint, infos); err != nil {
+		return nil, fmt.Errorf("cache wal segments: %w", err)
+	}
+	return litestream.NewWALSegmentInfoSliceIterator(infos), nil
+}
+
+// invalidateGeneration drops every cached listing for generation, plus the
+// generation list itself, since a write can change whether a generation is
+// the current one.
+func (c *ReplicaClient) invalidateGeneration(generation string) error {
+	if err := c.store.invalidate(c.url, "generations"); err != nil {
+		return err
+	}
+	if err := c.store.invalidate(c.url, "snapshots:"+generation); err != nil {
+		return err
+	}
+	return c.store.invalidate(c.url, "wal:"+generation)
+}
+
+func (c *ReplicaClient) WriteSnapshot(ctx context.Context, generation string, index int, r io.Reader) (litestream.SnapshotInfo, error) {
+	info, err := c.ReplicaClient.WriteSnapshot(ctx, generation, index, r)
+	if err != nil {
+		return info, err
+	}
+	return info, c.invalidateGeneration(generation)
+}
+
+func (c *ReplicaClient) WriteWALSegment(ctx context.Context, pos litestream.Pos, r io.Reader) (litestream.WALSegmentInfo, error) {
+	info, err := c.ReplicaClient.WriteWALSegment(ctx, pos, r)
+	if err != nil {
+		return info, err
+	}
+	return info, c.invalidateGeneration(pos.Generation)
+}
+
+func (c *ReplicaClient) DeleteWALSegments(ctx context.Context, a []litestream.Pos) error {
+	if err := c.ReplicaClient.DeleteWALSegments(ctx, a); err != nil {
+		return err
+	}
+	for _, pos := range a {
+		if err := c.invalidateGeneration(pos.Generation); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *ReplicaClient) DeleteGeneration(ctx context.Context, generation string) error {
+	if err := c.ReplicaClient.DeleteGeneration(ctx, generation); err != nil {
+		return err
+	}
+	return c.invalidateGeneration(generation)
+}
+
+func (c *ReplicaClient) DeleteSnapshot(ctx context.Context, generation string, index int) error {
+	if err := c.ReplicaClient.DeleteSnapshot(ctx, generation, index); err != nil {
+		return err
+	}
+	return c.invalidateGeneration(generation)
+}
+
+// WriteSnapshotWithMetadata forwards to the underlying client's
+// litestream.TaggedReplicaClient, so wrapping a tag-capable client with a
+// cache doesn't silently drop its support for tagged snapshots. Tagged
+// snapshots aren't themselves cached, so no listing needs invalidating
+// beyond the ordinary generation data a new snapshot can affect.
+func (c *ReplicaClient) WriteSnapshotWithMetadata(ctx context.Context, tag, generation string, index int, meta map[string]string, r io.Reader) (litestream.SnapshotInfo, error) {
+	tc, ok := c.ReplicaClient.(litestream.TaggedReplicaClient)
+	if !ok {
+		return litestream.SnapshotInfo{}, fmt.Errorf("replica client does not support tagged snapshots")
+	}
+	info, err := tc.WriteSnapshotWithMetadata(ctx, tag, generation, index, meta, r)
+	if err != nil {
+		return info, err
+	}
+	return info, c.invalidateGeneration(generation)
+}
+
+// TaggedSnapshotReader forwards to the underlying client's
+// litestream.TaggedReplicaClient. See WriteSnapshotWithMetadata.
+func (c *ReplicaClient) TaggedSnapshotReader(ctx context.Context, tag, generation string, index int) (io.ReadCloser, error) {
+	tc, ok := c.ReplicaClient.(litestream.TaggedReplicaClient)
+	if !ok {
+		return nil, fmt.Errorf("replica client does not support tagged snapshots")
+	}
+	return tc.TaggedSnapshotReader(ctx, tag, generation, index)
+}
+
+// TaggedSnapshots forwards to the underlying client's
+// litestream.TaggedReplicaClient. See WriteSnapshotWithMetadata.
+func (c *ReplicaClient) TaggedSnapshots(ctx context.Context, tag string) ([]litestream.SnapshotInfo, error) {
+	tc, ok := c.ReplicaClient.(litestream.TaggedReplicaClient)
+	if !ok {
+		return nil, fmt.Errorf("replica client does not support tagged snapshots")
+	}
+	return tc.TaggedSnapshots(ctx, tag)
+}
+
+// DeleteTaggedSnapshot forwards to the underlying client's
+// litestream.TaggedReplicaClient. See WriteSnapshotWithMetadata.
+func (c *ReplicaClient) DeleteTaggedSnapshot(ctx context.Context, tag, generation string, index int) error {
+	tc, ok := c.ReplicaClient.(litestream.TaggedReplicaClient)
+	if !ok {
+		return fmt.Errorf("replica client does not support tagged snapshots")
+	}
+	if err := tc.DeleteTaggedSnapshot(ctx, tag, generation, index); err != nil {
+		return err
+	}
+	return c.invalidateGeneration(generation)
+}
+
+// WALSegmentsAtLevel forwards to the underlying client's
+// litestream.LeveledReplicaClient, so wrapping a compaction-capable client
+// with a cache doesn't silently disable leveled compaction. Per-level
+// listings aren't themselves cached, so this always consults the
+// underlying client directly.
+func (c *ReplicaClient) WALSegmentsAtLevel(ctx context.Context, generation string, index, level int) (litestream.WALSegmentIterator, error) {
+	lc, ok := c.ReplicaClient.(litestream.LeveledReplicaClient)
+	if !ok {
+		return nil, fmt.Errorf("replica client does not support leveled compaction")
+	}
+	return lc.WALSegmentsAtLevel(ctx, generation, index, level)
+}
+
+// WALSegmentReaderAtLevel forwards to the underlying client's
+// litestream.LeveledReplicaClient. See WALSegmentsAtLevel.
+func (c *ReplicaClient) WALSegmentReaderAtLevel(ctx context.Context, generation string, index, level int, offset int64) (io.ReadCloser, error) {
+	lc, ok := c.ReplicaClient.(litestream.LeveledReplicaClient)
+	if !ok {
+		return nil, fmt.Errorf("replica client does not support leveled compaction")
+	}
+	return lc.WALSegmentReaderAtLevel(ctx, generation, index, level, offset)
+}
+
+// WriteCompactedWALSegment forwards to the underlying client's
+// litestream.LeveledReplicaClient. See WALSegmentsAtLevel.
+func (c *ReplicaClient) WriteCompactedWALSegment(ctx context.Context, generation string, index, level int, offset int64, r io.Reader) (litestream.WALSegmentInfo, error) {
+	lc, ok := c.ReplicaClient.(litestream.LeveledReplicaClient)
+	if !ok {
+		return litestream.WALSegmentInfo{}, fmt.Errorf("replica client does not support leveled compaction")
+	}
+	info, err := lc.WriteCompactedWALSegment(ctx, generation, index, level, offset, r)
+	if err != nil {
+		return info, err
+	}
+	return info, c.invalidateGeneration(generation)
+}
+
+// DeleteWALSegmentsAtLevel forwards to the underlying client's
+// litestream.LeveledReplicaClient. See WALSegmentsAtLevel.
+func (c *ReplicaClient) DeleteWALSegmentsAtLevel(ctx context.Context, generation string, index, level int, offsets []int64) error {
+	lc, ok := c.ReplicaClient.(litestream.LeveledReplicaClient)
+	if !ok {
+		return fmt.Errorf("replica client does not support leveled compaction")
+	}
+	if err := lc.DeleteWALSegmentsAtLevel(ctx, generation, index, level, offsets); err != nil {
+		return err
+	}
+	return c.invalidateGeneration(generation)
+}
@@ -0,0 +1,190 @@
+package cache_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/benbjohnson/litestream"
+	"github.com/benbjohnson/litestream/cache"
+	"github.com/benbjohnson/litestream/file"
+)
+
+func TestReplicaClient_Generations(t *testing.T) {
+	inner := file.NewReplicaClient(t.TempDir())
+
+	store, err := cache.Open(t.TempDir() + "/cache.db")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+
+	c := cache.Wrap(inner, "file:///test", store)
+	ctx := context.Background()
+
+	if _, err := c.WriteSnapshot(ctx, "0000000000000001", 0, strings.NewReader("snapshot")); err != nil {
+		t.Fatal(err)
+	}
+
+	generations, err := c.Generations(ctx)
+	if err != nil {
+		t.Fatal(err)
+	} else if got, want := len(generations), 1; got != want {
+		t.Fatalf("len(generations)=%v, want %v", got, want)
+	}
+
+	// Write a second generation directly against the inner client, bypassing
+	// the cache's own invalidation. file.ReplicaClient implements
+	// litestream.Fingerprinter, so the cache should detect the change on
+	// its own and not require an explicit Purge.
+	if _, err := inner.WriteSnapshot(ctx, "0000000000000002", 0, strings.NewReader("snapshot")); err != nil {
+		t.Fatal(err)
+	}
+	if generations, err := c.Generations(ctx); err != nil {
+		t.Fatal(err)
+	} else if got, want := len(generations), 2; got != want {
+		t.Fatalf("len(generations) after external write=%v, want %v (fingerprint should have detected the change)", got, want)
+	}
+}
+
+// TestReplicaClient_NoFingerprint verifies the documented fallback for a
+// client that doesn't implement litestream.Fingerprinter: listings are
+// cached until explicitly invalidated, since there's no other way to
+// detect an external change.
+func TestReplicaClient_NoFingerprint(t *testing.T) {
+	inner := &nonFingerprintingClient{ReplicaClient: file.NewReplicaClient(t.TempDir())}
+
+	store, err := cache.Open(t.TempDir() + "/cache.db")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+
+	c := cache.Wrap(inner, "file:///test", store)
+	ctx := context.Background()
+
+	if _, err := c.WriteSnapshot(ctx, "0000000000000001", 0, strings.NewReader("snapshot")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.Generations(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := inner.WriteSnapshot(ctx, "0000000000000002", 0, strings.NewReader("snapshot")); err != nil {
+		t.Fatal(err)
+	}
+	if generations, err := c.Generations(ctx); err != nil {
+		t.Fatal(err)
+	} else if got, want := len(generations), 1; got != want {
+		t.Fatalf("len(generations) before purge=%v, want %v (expected stale cache)", got, want)
+	}
+
+	if err := store.Purge(); err != nil {
+		t.Fatal(err)
+	}
+	if generations, err := c.Generations(ctx); err != nil {
+		t.Fatal(err)
+	} else if got, want := len(generations), 2; got != want {
+		t.Fatalf("len(generations) after purge=%v, want %v", got, want)
+	}
+}
+
+// TestReplicaClient_Generations_ExistingGeneration verifies that a second
+// snapshot written directly against the inner client, into a generation the
+// cache has already listed, is picked up without an explicit Purge. A
+// generation directory's own mtime doesn't change when a file is added
+// inside its already-existing snapshots directory, so the fingerprint must
+// be sensitive to that too, not just to a brand-new generation appearing.
+func TestReplicaClient_Generations_ExistingGeneration(t *testing.T) {
+	inner := file.NewReplicaClient(t.TempDir())
+
+	store, err := cache.Open(t.TempDir() + "/cache.db")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+
+	c := cache.Wrap(inner, "file:///test", store)
+	ctx := context.Background()
+
+	if _, err := c.WriteSnapshot(ctx, "0000000000000001", 0, strings.NewReader("snapshot")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.Snapshots(ctx, "0000000000000001"); err != nil {
+		t.Fatal(err)
+	}
+
+	// Write a second snapshot directly against the inner client, into the
+	// same, already-cached generation, bypassing the cache's invalidation.
+	if _, err := inner.WriteSnapshot(ctx, "0000000000000001", 1, strings.NewReader("snapshot")); err != nil {
+		t.Fatal(err)
+	}
+	if infos, err := c.Snapshots(ctx, "0000000000000001"); err != nil {
+		t.Fatal(err)
+	} else if got, want := len(infos), 2; got != want {
+		t.Fatalf("len(Snapshots)=%v, want %v (fingerprint should have detected the external write)", got, want)
+	}
+}
+
+// TestReplicaClient_ForwardsCapabilities verifies that wrapping a client
+// with the cache doesn't silently drop its optional capabilities: a client
+// that implements litestream.TaggedReplicaClient or
+// litestream.LeveledReplicaClient must still satisfy those interfaces once
+// wrapped, so turning on caching doesn't quietly turn off tagged snapshots
+// or leveled compaction for that replica.
+func TestReplicaClient_ForwardsCapabilities(t *testing.T) {
+	inner := file.NewReplicaClient(t.TempDir())
+
+	store, err := cache.Open(t.TempDir() + "/cache.db")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+
+	c := cache.Wrap(inner, "file:///test", store)
+
+	if _, ok := any(c).(litestream.TaggedReplicaClient); !ok {
+		t.Fatal("cache-wrapped client no longer satisfies litestream.TaggedReplicaClient")
+	}
+	if _, ok := any(c).(litestream.LeveledReplicaClient); !ok {
+		t.Fatal("cache-wrapped client no longer satisfies litestream.LeveledReplicaClient")
+	}
+}
+
+// nonFingerprintingClient wraps a litestream.ReplicaClient without
+// exposing its litestream.Fingerprinter methods, to exercise the cache's
+// fallback path for clients that can't report a fingerprint.
+type nonFingerprintingClient struct {
+	litestream.ReplicaClient
+}
+
+func TestReplicaClient_InvalidatesOnWrite(t *testing.T) {
+	inner := file.NewReplicaClient(t.TempDir())
+
+	store, err := cache.Open(t.TempDir() + "/cache.db")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+
+	c := cache.Wrap(inner, "file:///test", store)
+	ctx := context.Background()
+
+	if _, err := c.WriteSnapshot(ctx, "0000000000000001", 0, strings.NewReader("snapshot")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.Generations(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	// A second write through the wrapper must invalidate the cached
+	// generation list rather than serve the stale, one-generation result.
+	if _, err := c.WriteSnapshot(ctx, "0000000000000002", 0, strings.NewReader("snapshot")); err != nil {
+		t.Fatal(err)
+	}
+	if generations, err := c.Generations(ctx); err != nil {
+		t.Fatal(err)
+	} else if got, want := len(generations), 2; got != want {
+		t.Fatalf("len(generations)=%v, want %v", got, want)
+	}
+}
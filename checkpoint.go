@@ -0,0 +1,11 @@
+package litestream
+
+// CheckpointMode represents the mode passed to the SQLite wal_checkpoint pragma.
+type CheckpointMode string
+
+const (
+	CheckpointModePassive  CheckpointMode = "PASSIVE"
+	CheckpointModeFull     CheckpointMode = "FULL"
+	CheckpointModeRestart  CheckpointMode = "RESTART"
+	CheckpointModeTruncate CheckpointMode = "TRUNCATE"
+)
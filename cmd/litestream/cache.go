@@ -0,0 +1,68 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+
+	"github.com/benbjohnson/litestream/cache"
+)
+
+// CacheCommand manages the local bbolt-backed listing cache (e.g.
+// "litestream cache purge").
+type CacheCommand struct{}
+
+// NewCacheCommand returns a new instance of CacheCommand.
+func NewCacheCommand() *CacheCommand {
+	return &CacheCommand{}
+}
+
+// Run executes the cache command.
+func (c *CacheCommand) Run(ctx context.Context, args []string) (err error) {
+	fs := flag.NewFlagSet("litestream-cache", flag.ContinueOnError)
+	configPath, noExpandEnv := registerConfigFlag(fs)
+	if err := fs.Parse(args); err != nil {
+		return err
+	} else if fs.NArg() < 1 {
+		return fmt.Errorf("usage: litestream cache purge")
+	}
+
+	config, err := ReadConfigFile(*configPath, !*noExpandEnv)
+	if err != nil {
+		return err
+	} else if config.CachePath == "" {
+		return fmt.Errorf("no cache configured")
+	}
+
+	switch cmd := fs.Arg(0); cmd {
+	case "purge":
+		return c.purge(config.CachePath)
+	default:
+		return fmt.Errorf("unknown cache subcommand: %q", cmd)
+	}
+}
+
+func (c *CacheCommand) purge(cachePath string) error {
+	store, err := cache.Open(cachePath)
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	if err := store.Purge(); err != nil {
+		return err
+	}
+	fmt.Printf("purged cache at %s\n", cachePath)
+	return nil
+}
+
+// Usage prints the help screen for the cache command.
+func (c *CacheCommand) Usage() {
+	fmt.Fprintln(flag.CommandLine.Output(), `
+The cache command manages the local bbolt-backed listing cache.
+
+Usage:
+
+	litestream cache purge
+`[1:])
+}
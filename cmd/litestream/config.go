@@ -0,0 +1,112 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/benbjohnson/litestream"
+	"github.com/benbjohnson/litestream/file"
+)
+
+// DefaultConfigPath is used when -config is not given on the command line.
+const DefaultConfigPath = "/etc/litestream.json"
+
+// Config is the top-level configuration for the litestream CLI.
+type Config struct {
+	// CachePath is the location of the bbolt-backed listing cache. Empty
+	// disables caching.
+	CachePath string `json:"cache-path"`
+
+	DBs []*DBConfig `json:"dbs"`
+}
+
+// DBConfig returns the configuration for the database at path, or nil if
+// path is not configured.
+func (c *Config) DBConfig(path string) *DBConfig {
+	for _, dbc := range c.DBs {
+		if dbc.Path == path {
+			return dbc
+		}
+	}
+	return nil
+}
+
+// DBConfig is the configuration for a single replicated database.
+type DBConfig struct {
+	Path     string           `json:"path"`
+	Replicas []*ReplicaConfig `json:"replicas"`
+}
+
+// ReplicaConfig is the configuration for a single replica of a database.
+// Only the "file" replica type is currently supported.
+type ReplicaConfig struct {
+	Name string `json:"name"`
+	Path string `json:"path"`
+}
+
+// registerConfigFlag registers the -config and -no-expand-env flags shared
+// by every subcommand that loads a Config.
+func registerConfigFlag(fs *flag.FlagSet) (configPath *string, noExpandEnv *bool) {
+	configPath = fs.String("config", DefaultConfigPath, "config path")
+	noExpandEnv = fs.Bool("no-expand-env", false, "do not expand env vars in config")
+	return configPath, noExpandEnv
+}
+
+// ReadConfigFile reads and parses the config file at path. If expandEnv is
+// true, ${VAR}-style references are expanded against the environment
+// before parsing.
+func ReadConfigFile(path string, expandEnv bool) (*Config, error) {
+	buf, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read config: %w", err)
+	}
+	if expandEnv {
+		buf = []byte(os.Expand(string(buf), os.Getenv))
+	}
+
+	config := &Config{}
+	if err := json.Unmarshal(buf, config); err != nil {
+		return nil, fmt.Errorf("parse config: %w", err)
+	}
+	return config, nil
+}
+
+// newDBFromConfig returns a new litestream.DB configured per dbc, with one
+// litestream.Replica per configured replica. It does not open litestream's
+// connection to dbc.Path, and so never touches (or creates) the file at
+// dbc.Path - commands like restore, where the source may be missing or
+// corrupt, must be able to build a DB purely to drive its Replicas without
+// that side effect. Commands that need DB.Pos() to report the database's
+// real generation, e.g. SnapshotWithTag, should call openDBFromConfig
+// instead.
+func newDBFromConfig(dbc *DBConfig) (*litestream.DB, error) {
+	db := litestream.NewDB(dbc.Path)
+	for _, rc := range dbc.Replicas {
+		if rc.Path == "" {
+			return nil, fmt.Errorf("replica %q: path required", rc.Name)
+		}
+		r := litestream.NewReplica(db, rc.Name)
+		r.Client = file.NewReplicaClient(rc.Path)
+		db.Replicas = append(db.Replicas, r)
+	}
+	return db, nil
+}
+
+// openDBFromConfig is newDBFromConfig plus opening litestream's connection
+// to dbc.Path, which loads the database's existing generation id (or
+// establishes one if none exists yet). Callers must Close the returned DB
+// when done. Only use this for commands that actually read DB.Pos() against
+// a database expected to exist and be live - it creates dbc.Path (and its
+// -litestream meta dir) if either is missing.
+func openDBFromConfig(dbc *DBConfig) (*litestream.DB, error) {
+	db, err := newDBFromConfig(dbc)
+	if err != nil {
+		return nil, err
+	}
+	if err := db.Open(); err != nil {
+		return nil, fmt.Errorf("open %s: %w", dbc.Path, err)
+	}
+	return db, nil
+}
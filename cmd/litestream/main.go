@@ -0,0 +1,44 @@
+// Command litestream provides a CLI for managing and restoring from
+// litestream replicas: the bbolt listing cache, tagged snapshots, and
+// point-in-time restore.
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// command is implemented by every litestream subcommand.
+type command interface {
+	Run(ctx context.Context, args []string) error
+	Usage()
+}
+
+func main() {
+	if err := run(context.Background(), os.Args[1:]); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+func run(ctx context.Context, args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("usage: litestream <command> [arguments]")
+	}
+
+	var cmd command
+	switch args[0] {
+	case "cache":
+		cmd = NewCacheCommand()
+	case "restore":
+		cmd = NewRestoreCommand()
+	case "snapshots":
+		cmd = NewSnapshotsCommand()
+	default:
+		return fmt.Errorf("unknown command: %q", args[0])
+	}
+	return cmd.Run(ctx, args[1:])
+}
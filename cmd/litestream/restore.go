@@ -0,0 +1,84 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+
+	"github.com/benbjohnson/litestream"
+	"github.com/benbjohnson/litestream/cache"
+)
+
+// RestoreCommand reconstructs a database from its replica (e.g.
+// "litestream restore -o /tmp/restored.db mydb").
+type RestoreCommand struct{}
+
+// NewRestoreCommand returns a new instance of RestoreCommand.
+func NewRestoreCommand() *RestoreCommand {
+	return &RestoreCommand{}
+}
+
+// Run executes the restore command.
+func (c *RestoreCommand) Run(ctx context.Context, args []string) (err error) {
+	fs := flag.NewFlagSet("litestream-restore", flag.ContinueOnError)
+	configPath, noExpandEnv := registerConfigFlag(fs)
+	output := fs.String("o", "", "output path")
+	tag := fs.String("snapshot-tag", "", "restore up to this tagged snapshot")
+	throughNow := fs.Bool("through-now", false, "with -snapshot-tag, replay WAL past the tag's own index up to the newest segment available")
+	maxLevel := fs.Int("max-level", 3, "highest compaction level to read from")
+	if err := fs.Parse(args); err != nil {
+		return err
+	} else if *output == "" {
+		return fmt.Errorf("output path required: -o PATH")
+	} else if fs.NArg() != 1 {
+		return fmt.Errorf("usage: litestream restore -o PATH DB")
+	}
+
+	config, err := ReadConfigFile(*configPath, !*noExpandEnv)
+	if err != nil {
+		return err
+	}
+
+	dbc := config.DBConfig(fs.Arg(0))
+	if dbc == nil {
+		return fmt.Errorf("database not found in config: %s", fs.Arg(0))
+	}
+
+	db, err := newDBFromConfig(dbc)
+	if err != nil {
+		return err
+	} else if len(db.Replicas) == 0 {
+		return fmt.Errorf("no replicas configured for database: %s", fs.Arg(0))
+	}
+
+	if config.CachePath != "" {
+		store, err := cache.WrapReplica(db.Replicas[0], config.CachePath)
+		if err != nil {
+			return fmt.Errorf("wrap cache: %w", err)
+		}
+		defer store.Close()
+	}
+
+	pos, err := db.Replicas[0].Restore(ctx, *output, litestream.RestoreOptions{
+		Tag:        *tag,
+		ThroughNow: *throughNow,
+		MaxLevel:   *maxLevel,
+	})
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("restored %s to %s\n", fs.Arg(0), pos)
+	return nil
+}
+
+// Usage prints the help screen for the restore command.
+func (c *RestoreCommand) Usage() {
+	fmt.Fprintln(flag.CommandLine.Output(), `
+The restore command reconstructs a database from its replica.
+
+Usage:
+
+	litestream restore -o PATH [-snapshot-tag NAME [-through-now]] DB
+`[1:])
+}
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+
+	"github.com/benbjohnson/litestream"
+	"github.com/benbjohnson/litestream/cache"
+)
+
+// SnapshotsCommand manages tagged, retention-exempt snapshots (e.g.
+// "litestream snapshots -tag pre-migration create mydb").
+type SnapshotsCommand struct{}
+
+// NewSnapshotsCommand returns a new instance of SnapshotsCommand.
+func NewSnapshotsCommand() *SnapshotsCommand {
+	return &SnapshotsCommand{}
+}
+
+// Run executes the snapshots command.
+func (c *SnapshotsCommand) Run(ctx context.Context, args []string) (err error) {
+	fs := flag.NewFlagSet("litestream-snapshots", flag.ContinueOnError)
+	configPath, noExpandEnv := registerConfigFlag(fs)
+	tag := fs.String("tag", "", "snapshot tag")
+	if err := fs.Parse(args); err != nil {
+		return err
+	} else if *tag == "" {
+		return fmt.Errorf("tag required: -tag NAME")
+	} else if fs.NArg() < 2 {
+		return fmt.Errorf("usage: litestream snapshots -tag NAME create|list|delete DB")
+	}
+
+	config, err := ReadConfigFile(*configPath, !*noExpandEnv)
+	if err != nil {
+		return err
+	}
+
+	dbc := config.DBConfig(fs.Arg(1))
+	if dbc == nil {
+		return fmt.Errorf("database not found in config: %s", fs.Arg(1))
+	}
+
+	cmd := fs.Arg(0)
+
+	// Only "create" reads DB.Pos() (to tag the live generation), so only it
+	// needs the database actually opened; "list" and "delete" work purely
+	// against the replica and must not touch DB.Path() as a side effect.
+	var db *litestream.DB
+	if cmd == "create" {
+		db, err = openDBFromConfig(dbc)
+		if err != nil {
+			return err
+		}
+		defer db.Close()
+	} else {
+		db, err = newDBFromConfig(dbc)
+		if err != nil {
+			return err
+		}
+	}
+	if len(db.Replicas) == 0 {
+		return fmt.Errorf("no replicas configured for database: %s", fs.Arg(1))
+	}
+	r := db.Replicas[0]
+
+	if config.CachePath != "" {
+		store, err := cache.WrapReplica(r, config.CachePath)
+		if err != nil {
+			return fmt.Errorf("wrap cache: %w", err)
+		}
+		defer store.Close()
+	}
+
+	switch cmd {
+	case "create":
+		return c.create(ctx, r, *tag)
+	case "list":
+		return c.list(ctx, r, *tag)
+	case "delete":
+		if fs.NArg() < 3 {
+			return fmt.Errorf("usage: litestream snapshots -tag NAME delete DB INDEX")
+		}
+		return c.delete(ctx, r, *tag, fs.Arg(2))
+	default:
+		return fmt.Errorf("unknown snapshots subcommand: %q", cmd)
+	}
+}
+
+func (c *SnapshotsCommand) create(ctx context.Context, r *litestream.Replica, tag string) error {
+	info, err := r.SnapshotWithTag(ctx, tag, nil)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("created tagged snapshot %q at %s\n", tag, info.Pos())
+	return nil
+}
+
+func (c *SnapshotsCommand) list(ctx context.Context, r *litestream.Replica, tag string) error {
+	infos, err := r.ListTaggedSnapshots(ctx, tag)
+	if err != nil {
+		return err
+	}
+	for _, info := range infos {
+		fmt.Printf("%s\t%s\n", info.Pos(), info.CreatedAt.Format("2006-01-02T15:04:05Z"))
+	}
+	return nil
+}
+
+func (c *SnapshotsCommand) delete(ctx context.Context, r *litestream.Replica, tag, index string) error {
+	infos, err := r.ListTaggedSnapshots(ctx, tag)
+	if err != nil {
+		return err
+	}
+	for _, info := range infos {
+		if fmt.Sprintf("%08x", info.Index) == index || fmt.Sprint(info.Index) == index {
+			if err := r.DeleteTaggedSnapshot(ctx, tag, info.Pos()); err != nil {
+				return err
+			}
+			fmt.Printf("deleted tagged snapshot %q at %s\n", tag, info.Pos())
+			return nil
+		}
+	}
+	return fmt.Errorf("no snapshot found for tag %q, index %q", tag, index)
+}
+
+// Usage prints the help screen for the snapshots command.
+func (c *SnapshotsCommand) Usage() {
+	fmt.Fprintln(flag.CommandLine.Output(), `
+The snapshots command manages tagged snapshots that are excluded from the
+normal, time-based retention GC.
+
+Usage:
+
+	litestream snapshots -tag NAME create DB
+	litestream snapshots -tag NAME list DB
+	litestream snapshots -tag NAME delete DB INDEX
+`[1:])
+}
@@ -0,0 +1,300 @@
+package litestream
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/pierrec/lz4/v4"
+)
+
+const (
+	// DefaultCompactionLevels is the default value for Compactor.Levels.
+	DefaultCompactionLevels = 3
+
+	// DefaultCompactionL0Threshold is the default value for
+	// Compactor.L0Threshold.
+	DefaultCompactionL0Threshold = 100
+
+	// DefaultCompactionRatio is the default value for Compactor.Ratio.
+	DefaultCompactionRatio = 10
+
+	// DefaultCompactionInterval is how often the compactor checks whether
+	// any generation has crossed a level threshold.
+	DefaultCompactionInterval = 1 * time.Minute
+)
+
+// LeveledReplicaClient is an optional capability that a ReplicaClient can
+// implement to support compaction. Clients that don't implement it simply
+// never have their segments compacted; Replica.Sync and restore continue to
+// work against L0 segments only.
+type LeveledReplicaClient interface {
+	// WALSegmentsAtLevel returns an iterator over WAL segments at level
+	// for the given generation and index, ordered by offset. Pass index -1
+	// to iterate segments at level across every index within generation,
+	// which is how Compactor discovers candidates to merge into the next
+	// level, at L0 and above.
+	WALSegmentsAtLevel(ctx context.Context, generation string, index, level int) (WALSegmentIterator, error)
+
+	// WALSegmentReaderAtLevel returns a reader for the compressed segment at
+	// (generation, index, level, offset), the level-aware counterpart to
+	// ReplicaClient.WALSegmentReader needed to read back a segment above L0 -
+	// to merge it into the next level, or for restore to replay it.
+	WALSegmentReaderAtLevel(ctx context.Context, generation string, index, level int, offset int64) (io.ReadCloser, error)
+
+	// WriteCompactedWALSegment writes r as a single segment at level,
+	// starting at offset (the offset of the first lower-level segment it
+	// was produced from), superseding the lower-level segments it
+	// supersedes.
+	WriteCompactedWALSegment(ctx context.Context, generation string, index, level int, offset int64, r io.Reader) (WALSegmentInfo, error)
+
+	// DeleteWALSegmentsAtLevel removes previously-compacted segments, used
+	// once a higher level supersedes them.
+	DeleteWALSegmentsAtLevel(ctx context.Context, generation string, index, level int, offsets []int64) error
+}
+
+// Compactor periodically merges a Replica's L0 WAL segments into larger,
+// higher-level segments, mirroring LevelDB-style leveled compaction. This
+// reduces the number of LIST/GET calls needed to restore a long-lived
+// generation without waiting for a full snapshot cycle.
+type Compactor struct {
+	Replica *Replica
+
+	// Levels is the number of compaction levels above L0, capped so
+	// compaction cannot run away on a generation with unbounded history.
+	Levels int
+
+	// L0Threshold is the number of L0 segments within an index that
+	// triggers a merge into L1. Each subsequent level multiplies the
+	// threshold by Ratio.
+	L0Threshold int
+
+	// Ratio is the multiplier applied to L0Threshold for each level above
+	// L0 (e.g. L1->L2 requires L0Threshold*Ratio segments at L1).
+	Ratio int
+
+	// Interval is how often the compactor checks for work.
+	Interval time.Duration
+
+	Logger *slog.Logger
+
+	cancel  func()
+	wg      sync.WaitGroup
+	mu      sync.Mutex
+	started bool
+}
+
+// NewCompactor returns a new instance of Compactor for replica with default
+// tunables.
+func NewCompactor(replica *Replica) *Compactor {
+	return &Compactor{
+		Replica:     replica,
+		Levels:      DefaultCompactionLevels,
+		L0Threshold: DefaultCompactionL0Threshold,
+		Ratio:       DefaultCompactionRatio,
+		Interval:    DefaultCompactionInterval,
+		Logger:      slog.With("replica", replica.Name()),
+	}
+}
+
+// Start begins the background compaction goroutine. It is a no-op if the
+// replica's client does not implement LeveledReplicaClient.
+func (c *Compactor) Start() {
+	if _, ok := c.Replica.Client.(LeveledReplicaClient); !ok {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.started {
+		return
+	}
+	c.started = true
+
+	ctx, cancel := context.WithCancel(context.Background())
+	c.cancel = cancel
+
+	c.wg.Add(1)
+	go func() {
+		defer c.wg.Done()
+		c.monitor(ctx)
+	}()
+}
+
+// Stop cancels the background compaction goroutine and waits for it to
+// exit.
+func (c *Compactor) Stop() {
+	c.mu.Lock()
+	cancel := c.cancel
+	c.started = false
+	c.mu.Unlock()
+
+	if cancel != nil {
+		cancel()
+	}
+	c.wg.Wait()
+}
+
+// monitor runs compaction on a timer until ctx is canceled.
+func (c *Compactor) monitor(ctx context.Context) {
+	ticker := time.NewTicker(c.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		if err := c.Compact(ctx); err != nil {
+			c.Logger.Error("compaction failed", "error", err)
+		}
+	}
+}
+
+// Compact checks every generation known to the replica and merges any
+// level whose segment count has crossed its threshold.
+func (c *Compactor) Compact(ctx context.Context) error {
+	client, ok := c.Replica.Client.(LeveledReplicaClient)
+	if !ok {
+		return nil // client does not support compaction
+	}
+
+	generations, err := c.Replica.Client.Generations(ctx)
+	if err != nil {
+		return fmt.Errorf("generations: %w", err)
+	}
+
+	for _, generation := range generations {
+		if err := c.compactGeneration(ctx, client, generation); err != nil {
+			return fmt.Errorf("compact generation %s: %w", generation, err)
+		}
+	}
+	return nil
+}
+
+// compactGeneration walks levels 0..Levels-1 for generation, merging each
+// level that has crossed its threshold into the next.
+func (c *Compactor) compactGeneration(ctx context.Context, client LeveledReplicaClient, generation string) error {
+	for level := 0; level < c.Levels; level++ {
+		threshold := c.thresholdForLevel(level)
+
+		indexes, err := c.indexesAtLevel(ctx, client, generation, level)
+		if err != nil {
+			return fmt.Errorf("indexes at level %d: %w", level, err)
+		}
+
+		for index, count := range indexes {
+			if count < threshold {
+				continue
+			}
+			if err := c.compactIndex(ctx, client, generation, index, level); err != nil {
+				return fmt.Errorf("compact index %d at level %d: %w", index, level, err)
+			}
+		}
+	}
+	return nil
+}
+
+// thresholdForLevel returns the segment count required to trigger a merge
+// of level into level+1.
+func (c *Compactor) thresholdForLevel(level int) int {
+	threshold := c.L0Threshold
+	for i := 0; i < level; i++ {
+		threshold *= c.Ratio
+	}
+	return threshold
+}
+
+// indexesAtLevel returns the number of segments present at level, keyed by
+// WAL index. A given index can accumulate more than one segment at a level
+// above L0 too: each compaction pass over an index produces one more
+// higher-level segment covering the offset range merged that pass, so
+// repeated passes over the same still-open index build up multiple
+// same-level segments for it, same as L0 does from repeated Syncs.
+func (c *Compactor) indexesAtLevel(ctx context.Context, client LeveledReplicaClient, generation string, level int) (map[int]int, error) {
+	itr, err := client.WALSegmentsAtLevel(ctx, generation, -1, level)
+	if err != nil {
+		return nil, err
+	}
+	defer itr.Close()
+
+	counts := make(map[int]int)
+	for itr.Next() {
+		counts[itr.WALSegment().Index]++
+	}
+	return counts, itr.Close()
+}
+
+// compactIndex merges every segment at (generation, index, level) into a
+// single segment at level+1, then deletes the superseded segments. The
+// higher-level segment is written and listed durably before the lower
+// level is removed, so a crash mid-compaction just leaves stale L-level
+// segments that the next pass retries.
+func (c *Compactor) compactIndex(ctx context.Context, client LeveledReplicaClient, generation string, index, level int) error {
+	// A ReadHandle reconstructing its pinned position may still need these
+	// L-level segments; leave every index at or below the lowest pinned
+	// index in place rather than risk pulling the rug out from under it.
+	if minIndex, ok := MinActiveHandleIndex(c.Replica.db); ok && index <= minIndex {
+		return nil
+	}
+
+	itr, err := client.WALSegmentsAtLevel(ctx, generation, index, level)
+	if err != nil {
+		return fmt.Errorf("list segments: %w", err)
+	}
+	defer itr.Close()
+
+	var buf bytes.Buffer
+	zw := lz4.NewWriter(&buf)
+
+	var offsets []int64
+	for itr.Next() {
+		info := itr.WALSegment()
+		offsets = append(offsets, info.Offset)
+
+		if err := c.appendSegment(ctx, client, zw, generation, level, info); err != nil {
+			return fmt.Errorf("append segment at offset %d: %w", info.Offset, err)
+		}
+	}
+	if err := itr.Close(); err != nil {
+		return err
+	}
+	if err := zw.Close(); err != nil {
+		return fmt.Errorf("close compressor: %w", err)
+	}
+	if len(offsets) == 0 {
+		return nil
+	}
+
+	if _, err := client.WriteCompactedWALSegment(ctx, generation, index, level+1, offsets[0], &buf); err != nil {
+		return fmt.Errorf("write compacted segment: %w", err)
+	}
+
+	if err := client.DeleteWALSegmentsAtLevel(ctx, generation, index, level, offsets); err != nil {
+		return fmt.Errorf("delete superseded segments: %w", err)
+	}
+
+	c.Logger.Info("compacted wal segments",
+		"generation", generation, "index", index, "level", level+1, "segments", len(offsets))
+	return nil
+}
+
+// appendSegment decompresses a single segment at (generation, level,
+// info.Offset) and writes its raw bytes into zw, so contiguous segments
+// concatenate into one continuous byte range at the next level.
+func (c *Compactor) appendSegment(ctx context.Context, client LeveledReplicaClient, zw io.Writer, generation string, level int, info WALSegmentInfo) error {
+	r, err := client.WALSegmentReaderAtLevel(ctx, generation, info.Index, level, info.Offset)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	_, err = io.Copy(zw, lz4.NewReader(r))
+	return err
+}
@@ -0,0 +1,42 @@
+package litestream
+
+import "context"
+
+// WALSegmentRange describes a contiguous byte range at a given generation,
+// index and level, as reported by a LeveledReplicaClient.
+type WALSegmentRange struct {
+	Info  WALSegmentInfo
+	Level int
+}
+
+// SelectWALSegments returns the smallest set of segments that cover every
+// byte of index, preferring the highest available level for any given byte
+// range so restore reads fewer, larger objects. Lower-level segments are
+// only included where no higher-level segment covers their range yet.
+//
+// This is used by the restore path in place of reading L0 segments
+// directly once a client implements LeveledReplicaClient.
+func SelectWALSegments(ctx context.Context, client LeveledReplicaClient, generation string, index, maxLevel int) ([]WALSegmentRange, error) {
+	var ranges []WALSegmentRange
+	var covered int64 // offset up to which a higher level has already been selected
+
+	for level := maxLevel; level >= 0; level-- {
+		itr, err := client.WALSegmentsAtLevel(ctx, generation, index, level)
+		if err != nil {
+			return nil, err
+		}
+
+		for itr.Next() {
+			info := itr.WALSegment()
+			if info.Offset < covered {
+				continue // superseded by a segment already selected at a higher level
+			}
+			ranges = append(ranges, WALSegmentRange{Info: info, Level: level})
+			covered = info.Offset + info.Size
+		}
+		if err := itr.Close(); err != nil {
+			return nil, err
+		}
+	}
+	return ranges, nil
+}
@@ -0,0 +1,341 @@
+package litestream
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DB replicates a single SQLite database in WAL mode to zero or more
+// replicas. It holds its own internal connection to the database, separate
+// from any connection the application uses, so it can checkpoint and
+// inspect the WAL without interfering with application writes.
+type DB struct {
+	path string
+
+	mu         sync.RWMutex
+	sqldb      *sql.DB
+	generation string
+	index      int
+
+	watchCancel func()
+	watchWg     sync.WaitGroup
+
+	// Replicas is the set of destinations this database replicates to.
+	Replicas []*Replica
+
+	// MonitorInterval is how often a background goroutine would call Sync.
+	// Tests set this to 0 to disable the goroutine and drive Sync by hand.
+	MonitorInterval time.Duration
+
+	// MaxHandleAge bounds how long a ReadHandle acquired from this DB may
+	// pin GC before Open's watchdog goroutine force-releases it. Set this
+	// to 0 to disable the watchdog goroutine.
+	MaxHandleAge time.Duration
+
+	Logger *slog.Logger
+}
+
+// NewDB returns a new instance of DB for the SQLite database at path.
+func NewDB(path string) *DB {
+	return &DB{
+		path:            path,
+		MonitorInterval: 1 * time.Second,
+		MaxHandleAge:    DefaultMaxHandleAge,
+		Logger:          slog.With("db", path),
+	}
+}
+
+// Path returns the path to the underlying SQLite database.
+func (db *DB) Path() string { return db.path }
+
+// MetaPath returns the path to the directory litestream uses to track
+// per-database metadata (currently just the generation id).
+func (db *DB) MetaPath() string { return db.path + "-litestream" }
+
+// Open opens litestream's own connection to the database and establishes a
+// generation if one doesn't already exist for it.
+func (db *DB) Open() (err error) {
+	if err := os.MkdirAll(db.MetaPath(), 0o750); err != nil {
+		return fmt.Errorf("mkdir meta dir: %w", err)
+	}
+
+	sqldb, err := sql.Open("sqlite3", db.path)
+	if err != nil {
+		return fmt.Errorf("open sqlite: %w", err)
+	}
+	if _, err := sqldb.Exec(`PRAGMA journal_mode = wal;`); err != nil {
+		sqldb.Close()
+		return fmt.Errorf("enable wal mode: %w", err)
+	}
+
+	db.mu.Lock()
+	db.sqldb = sqldb
+	db.mu.Unlock()
+
+	if err := db.ensureGeneration(); err != nil {
+		return err
+	}
+
+	if db.MaxHandleAge > 0 {
+		db.startHandleWatchdog()
+	}
+	return nil
+}
+
+// startHandleWatchdog runs WatchStaleHandles on a timer until Close stops
+// it, so a leaked ReadHandle can't pin retention or compaction GC forever.
+func (db *DB) startHandleWatchdog() {
+	ctx, cancel := context.WithCancel(context.Background())
+	db.watchCancel = cancel
+
+	db.watchWg.Add(1)
+	go func() {
+		defer db.watchWg.Done()
+
+		ticker := time.NewTicker(db.MaxHandleAge)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				WatchStaleHandles(db, db.MaxHandleAge)
+			}
+		}
+	}()
+}
+
+// Close closes litestream's connection to the database.
+func (db *DB) Close() error {
+	db.mu.Lock()
+	cancel := db.watchCancel
+	db.watchCancel = nil
+	db.mu.Unlock()
+
+	if cancel != nil {
+		cancel()
+		db.watchWg.Wait()
+	}
+
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	if db.sqldb == nil {
+		return nil
+	}
+	err := db.sqldb.Close()
+	db.sqldb = nil
+	return err
+}
+
+// ensureGeneration creates a new, random generation id the first time it's
+// called for this database, and loads the current index, if any.
+func (db *DB) ensureGeneration() error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	if db.generation != "" {
+		return nil
+	}
+
+	generationPath := filepath.Join(db.MetaPath(), "generation")
+	b, err := os.ReadFile(generationPath)
+	switch {
+	case err == nil:
+		db.generation = strings.TrimSpace(string(b))
+	case os.IsNotExist(err):
+		buf := make([]byte, 16)
+		if _, err := rand.Read(buf); err != nil {
+			return fmt.Errorf("generate generation id: %w", err)
+		}
+		db.generation = hex.EncodeToString(buf)
+		if err := os.WriteFile(generationPath, []byte(db.generation), 0o640); err != nil {
+			return fmt.Errorf("write generation id: %w", err)
+		}
+	default:
+		return fmt.Errorf("read generation id: %w", err)
+	}
+
+	indexPath := filepath.Join(db.MetaPath(), "index")
+	if b, err := os.ReadFile(indexPath); err == nil {
+		index, err := strconv.Atoi(strings.TrimSpace(string(b)))
+		if err != nil {
+			return fmt.Errorf("parse index: %w", err)
+		}
+		db.index = index
+	} else if !os.IsNotExist(err) {
+		return fmt.Errorf("read index: %w", err)
+	}
+
+	return nil
+}
+
+// Sync ensures the database has an established generation. Applications
+// call this after writing to the database and before replicas sync from
+// it.
+func (db *DB) Sync(ctx context.Context) error {
+	return db.ensureGeneration()
+}
+
+// Pos returns the database's current position: its generation, the index
+// of the current (uncheckpointed) WAL, and the WAL's current size.
+func (db *DB) Pos() Pos {
+	db.mu.RLock()
+	pos := Pos{Generation: db.generation, Index: db.index}
+	db.mu.RUnlock()
+
+	if fi, err := os.Stat(db.path + "-wal"); err == nil {
+		pos.Offset = fi.Size()
+	}
+	return pos
+}
+
+// Checkpoint runs a SQLite WAL checkpoint in the given mode. A
+// CheckpointModeTruncate checkpoint starts a new index, since the WAL it
+// truncates can no longer be appended to.
+func (db *DB) Checkpoint(ctx context.Context, mode CheckpointMode) error {
+	db.mu.RLock()
+	sqldb := db.sqldb
+	db.mu.RUnlock()
+
+	if _, err := sqldb.ExecContext(ctx, fmt.Sprintf("PRAGMA wal_checkpoint(%s);", mode)); err != nil {
+		return fmt.Errorf("wal_checkpoint(%s): %w", mode, err)
+	}
+
+	if mode != CheckpointModeTruncate {
+		return nil
+	}
+
+	db.mu.Lock()
+	db.index++
+	index := db.index
+	db.mu.Unlock()
+
+	if err := os.WriteFile(filepath.Join(db.MetaPath(), "index"), []byte(strconv.Itoa(index)), 0o640); err != nil {
+		return fmt.Errorf("write index: %w", err)
+	}
+	return nil
+}
+
+// WALReader returns a reader of the current index's WAL file starting at
+// offset, for a Replica to compress and push to its client.
+func (db *DB) WALReader(offset int64) (io.ReadCloser, error) {
+	f, err := os.Open(db.path + "-wal")
+	if err != nil {
+		return nil, err
+	}
+	if _, err := f.Seek(offset, io.SeekStart); err != nil {
+		f.Close()
+		return nil, err
+	}
+	return f, nil
+}
+
+// restoreTo reconstructs the database as of pos into destPath. While db's
+// live index still matches pos.Index, it copies the main database file as
+// it stands now along with the bytes of the current index's WAL up to
+// pos.Offset (restoreFromLive): MinActiveHandleIndex keeps Compactor and
+// EnforceRetention from deleting a pinned index's segments, but a
+// CheckpointModeTruncate checkpoint isn't blocked by a ReadHandle and
+// advances db's live index and -wal file out from under it, so restoreTo
+// falls back to reconstructing pos from a configured replica instead
+// (restoreFromReplica), the same snapshot + WAL-replay machinery
+// Replica.Restore uses.
+func (db *DB) restoreTo(ctx context.Context, destPath string, pos Pos) error {
+	db.mu.RLock()
+	generation, index := db.generation, db.index
+	db.mu.RUnlock()
+
+	if generation == pos.Generation && index == pos.Index {
+		return db.restoreFromLive(destPath, pos)
+	}
+	return db.restoreFromReplica(ctx, destPath, pos)
+}
+
+// restoreFromLive copies the main database file as it stands now along
+// with the bytes of the current index's WAL up to pos.Offset. Only valid
+// while db's live position still matches pos (see restoreTo).
+func (db *DB) restoreFromLive(destPath string, pos Pos) error {
+	if err := copyFile(db.path, destPath); err != nil {
+		return fmt.Errorf("copy database: %w", err)
+	}
+	if pos.Offset == 0 {
+		return nil
+	}
+	if err := copyFilePrefix(db.path+"-wal", destPath+"-wal", pos.Offset); err != nil {
+		return fmt.Errorf("copy wal: %w", err)
+	}
+	return nil
+}
+
+// restoreFromReplica reconstructs destPath as of pos.Generation from the
+// first configured replica with a client, using the same snapshot +
+// WAL-replay machinery Replica.Restore uses for a Generation-based restore.
+// It can fall short of pos itself if the bytes up to pos hadn't been synced
+// yet when the checkpoint that invalidated restoreFromLive ran; callers
+// needing the exact live position should Sync before Acquire.
+func (db *DB) restoreFromReplica(ctx context.Context, destPath string, pos Pos) error {
+	for _, r := range db.Replicas {
+		if r.Client == nil {
+			continue
+		}
+		if _, err := r.Restore(ctx, destPath, RestoreOptions{
+			Generation: pos.Generation,
+			MaxLevel:   DefaultCompactionLevels,
+		}); err != nil {
+			return fmt.Errorf("restore from replica %s: %w", r.Name(), err)
+		}
+		return nil
+	}
+	return fmt.Errorf("position %s is no longer current and no replica is configured to reconstruct it from", pos)
+}
+
+func copyFile(src, dst string) error {
+	r, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	w, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	defer w.Close()
+
+	if _, err := io.Copy(w, r); err != nil {
+		return err
+	}
+	return w.Sync()
+}
+
+func copyFilePrefix(src, dst string, n int64) error {
+	r, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	w, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	defer w.Close()
+
+	if _, err := io.CopyN(w, r, n); err != nil {
+		return err
+	}
+	return w.Sync()
+}
@@ -0,0 +1,179 @@
+package file
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/benbjohnson/litestream"
+)
+
+// levelDir returns the directory holding segments for (generation, index,
+// level). Level 0 segments continue to live alongside the uncompacted WAL
+// files written by Sync, i.e. directly in the index directory; only level
+// >= 1 segments, the output of litestream.Compactor, are stored in a
+// level-specific subdirectory.
+func (c *ReplicaClient) levelDir(generation string, index, level int) string {
+	if level == 0 {
+		return c.indexDir(generation, index)
+	}
+	return filepath.Join(c.indexDir(generation, index), fmt.Sprintf("L%d", level))
+}
+
+// WALSegmentsAtLevel returns an iterator over WAL segments at level for
+// generation and index. Pass index -1 to iterate segments at level across
+// every index within the generation, which is how litestream.Compactor
+// discovers merge candidates, at L0 and at every level above it.
+func (c *ReplicaClient) WALSegmentsAtLevel(ctx context.Context, generation string, index, level int) (litestream.WALSegmentIterator, error) {
+	if index < 0 {
+		return c.walSegmentsAtLevelAllIndexes(ctx, generation, level)
+	}
+
+	dir := c.levelDir(generation, index, level)
+	ents, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return litestream.NewWALSegmentInfoSliceIterator(nil), nil
+	} else if err != nil {
+		return nil, fmt.Errorf("read level dir: %w", err)
+	}
+
+	var infos []litestream.WALSegmentInfo
+	for _, ent := range ents {
+		if ent.IsDir() || !strings.HasSuffix(ent.Name(), ".wal.lz4") {
+			continue
+		}
+
+		offset, err := strconv.ParseInt(strings.TrimSuffix(ent.Name(), ".wal.lz4"), 16, 64)
+		if err != nil {
+			continue
+		}
+
+		fi, err := ent.Info()
+		if err != nil {
+			return nil, err
+		}
+
+		infos = append(infos, litestream.WALSegmentInfo{
+			Generation: generation,
+			Index:      index,
+			Offset:     offset,
+			Size:       fi.Size(),
+			CreatedAt:  fi.ModTime().UTC(),
+		})
+	}
+
+	sort.Slice(infos, func(i, j int) bool { return infos[i].Offset < infos[j].Offset })
+	return litestream.NewWALSegmentInfoSliceIterator(infos), nil
+}
+
+// walSegmentsAtLevelAllIndexes iterates every index directory within
+// generation and collects its segments at level, for the index=-1 case of
+// WALSegmentsAtLevel. litestream.Compactor uses this to discover merge
+// candidates at any level, not just L0.
+func (c *ReplicaClient) walSegmentsAtLevelAllIndexes(ctx context.Context, generation string, level int) (litestream.WALSegmentIterator, error) {
+	genDir := c.generationDir(generation)
+	ents, err := os.ReadDir(genDir)
+	if os.IsNotExist(err) {
+		return litestream.NewWALSegmentInfoSliceIterator(nil), nil
+	} else if err != nil {
+		return nil, fmt.Errorf("read generation dir: %w", err)
+	}
+
+	var infos []litestream.WALSegmentInfo
+	for _, ent := range ents {
+		if !ent.IsDir() || ent.Name() == taggedSnapshotsDirName || ent.Name() == "snapshots" {
+			continue
+		}
+		index, err := strconv.ParseInt(ent.Name(), 16, 64)
+		if err != nil {
+			continue
+		}
+
+		itr, err := c.WALSegmentsAtLevel(ctx, generation, int(index), level)
+		if err != nil {
+			return nil, err
+		}
+		for itr.Next() {
+			infos = append(infos, itr.WALSegment())
+		}
+		if err := itr.Close(); err != nil {
+			return nil, err
+		}
+	}
+
+	sort.Slice(infos, func(i, j int) bool {
+		if infos[i].Index != infos[j].Index {
+			return infos[i].Index < infos[j].Index
+		}
+		return infos[i].Offset < infos[j].Offset
+	})
+	return litestream.NewWALSegmentInfoSliceIterator(infos), nil
+}
+
+// WALSegmentReaderAtLevel returns a reader for the compressed segment at
+// (generation, index, level, offset). Level 0 segments are addressed the
+// same way whether read through here or through WALSegmentReader.
+func (c *ReplicaClient) WALSegmentReaderAtLevel(ctx context.Context, generation string, index, level int, offset int64) (io.ReadCloser, error) {
+	dir := c.levelDir(generation, index, level)
+	return os.Open(filepath.Join(dir, fmt.Sprintf("%016x.wal.lz4", offset)))
+}
+
+// WriteCompactedWALSegment writes r as a single segment at level for
+// generation and index, starting at offset (the offset of the first
+// lower-level segment it was produced from), superseding the lower-level
+// segments it supersedes. r is the already-compressed byte stream, matching
+// the on-disk format of an ordinary L0 segment. The output file is named by
+// offset, the same way L0 segments are addressed, so a later compaction
+// pass over this level can derive its own segments' offsets the same way.
+func (c *ReplicaClient) WriteCompactedWALSegment(ctx context.Context, generation string, index, level int, offset int64, r io.Reader) (info litestream.WALSegmentInfo, err error) {
+	dir := c.levelDir(generation, index, level)
+	if err := os.MkdirAll(dir, 0o750); err != nil {
+		return info, fmt.Errorf("mkdir: %w", err)
+	}
+
+	filename := filepath.Join(dir, fmt.Sprintf("%016x.wal.lz4", offset))
+	f, err := os.Create(filename)
+	if err != nil {
+		return info, fmt.Errorf("create: %w", err)
+	}
+	defer f.Close()
+
+	n, err := io.Copy(f, r)
+	if err != nil {
+		return info, fmt.Errorf("copy: %w", err)
+	}
+	if err := f.Sync(); err != nil {
+		return info, fmt.Errorf("sync: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		return info, fmt.Errorf("close: %w", err)
+	}
+
+	return litestream.WALSegmentInfo{
+		Generation: generation,
+		Index:      index,
+		Offset:     offset,
+		Size:       n,
+	}, nil
+}
+
+// DeleteWALSegmentsAtLevel removes previously-compacted segments at offsets
+// within (generation, index, level). It is only ever called after the
+// higher-level segment that supersedes them has been durably written and
+// listed, so a crash between the two calls just leaves stale segments that
+// the next compaction pass retries.
+func (c *ReplicaClient) DeleteWALSegmentsAtLevel(ctx context.Context, generation string, index, level int, offsets []int64) error {
+	dir := c.levelDir(generation, index, level)
+	for _, offset := range offsets {
+		filename := filepath.Join(dir, fmt.Sprintf("%016x.wal.lz4", offset))
+		if err := os.Remove(filename); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("remove %s: %w", filename, err)
+		}
+	}
+	return nil
+}
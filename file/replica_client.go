@@ -0,0 +1,315 @@
+// Package file implements a litestream.ReplicaClient that reads and writes
+// generations, snapshots and WAL segments to a directory on local disk.
+package file
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/benbjohnson/litestream"
+)
+
+// taggedSnapshotsDirName is excluded from Generations, since it holds
+// tagged snapshots rather than a generation of WAL history.
+const taggedSnapshotsDirName = "snapshots-tagged"
+
+// ReplicaClient is a litestream.ReplicaClient backed by a directory on
+// local disk.
+type ReplicaClient struct {
+	path string
+
+	// Replica is set by callers that wire this client into a
+	// litestream.Replica, so client-side GC (e.g. DeleteWALSegments) can
+	// consult the same ReadHandle pinning invariant the Compactor and
+	// Replica.EnforceRetention already enforce.
+	Replica *litestream.Replica
+}
+
+// NewReplicaClient returns a new instance of ReplicaClient rooted at path.
+func NewReplicaClient(path string) *ReplicaClient {
+	return &ReplicaClient{path: path}
+}
+
+// Path returns the root directory the client reads and writes under.
+func (c *ReplicaClient) Path() string { return c.path }
+
+// Type returns "file" to identify the client type in logs.
+func (c *ReplicaClient) Type() string { return "file" }
+
+func (c *ReplicaClient) generationDir(generation string) string {
+	return filepath.Join(c.path, generation)
+}
+
+func (c *ReplicaClient) snapshotsDir(generation string) string {
+	return filepath.Join(c.generationDir(generation), "snapshots")
+}
+
+func (c *ReplicaClient) snapshotPath(generation string, index int) string {
+	return filepath.Join(c.snapshotsDir(generation), fmt.Sprintf("%08x.snapshot.lz4", index))
+}
+
+// indexDir returns the directory holding L0 WAL segments for generation
+// and index. Compacted (L1+) segments live in a subdirectory of this one;
+// see file/compaction.go.
+func (c *ReplicaClient) indexDir(generation string, index int) string {
+	return filepath.Join(c.generationDir(generation), fmt.Sprintf("%08x", index))
+}
+
+func (c *ReplicaClient) walSegmentPath(generation string, index int, offset int64) string {
+	return filepath.Join(c.indexDir(generation, index), fmt.Sprintf("%016x.wal.lz4", offset))
+}
+
+// GenerationsFingerprint implements litestream.Fingerprinter, using the
+// root directory's mtime, which the filesystem advances whenever a
+// generation is added or removed.
+func (c *ReplicaClient) GenerationsFingerprint(ctx context.Context) (string, error) {
+	return dirFingerprint(c.path)
+}
+
+// GenerationFingerprint implements litestream.Fingerprinter. The generation
+// directory's own mtime only advances when one of its direct children (the
+// snapshots directory or an index directory) is created or removed, not
+// when a file is added inside one that already exists - which is the
+// common case once a generation is established (a new snapshot landing in
+// an existing snapshots directory, a new WAL segment landing in an
+// existing index directory). So this combines the generation directory's
+// mtime with the mtime of each of its direct children.
+func (c *ReplicaClient) GenerationFingerprint(ctx context.Context, generation string) (string, error) {
+	dir := c.generationDir(generation)
+
+	fp, err := dirFingerprint(dir)
+	if err != nil {
+		return "", err
+	}
+
+	ents, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return fp, nil
+	} else if err != nil {
+		return "", fmt.Errorf("read dir: %w", err)
+	}
+
+	h := sha256.New()
+	io.WriteString(h, fp)
+	for _, ent := range ents {
+		childFP, err := dirFingerprint(filepath.Join(dir, ent.Name()))
+		if err != nil {
+			return "", err
+		}
+		io.WriteString(h, ent.Name())
+		io.WriteString(h, childFP)
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// dirFingerprint returns an opaque string derived from dir's mtime, or ""
+// if dir does not exist.
+func dirFingerprint(dir string) (string, error) {
+	fi, err := os.Stat(dir)
+	if os.IsNotExist(err) {
+		return "", nil
+	} else if err != nil {
+		return "", fmt.Errorf("stat: %w", err)
+	}
+	return strconv.FormatInt(fi.ModTime().UnixNano(), 16), nil
+}
+
+func (c *ReplicaClient) Generations(ctx context.Context) ([]string, error) {
+	ents, err := os.ReadDir(c.path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("read dir: %w", err)
+	}
+
+	var generations []string
+	for _, ent := range ents {
+		if !ent.IsDir() || ent.Name() == taggedSnapshotsDirName {
+			continue
+		}
+		generations = append(generations, ent.Name())
+	}
+	sort.Strings(generations)
+	return generations, nil
+}
+
+func (c *ReplicaClient) DeleteGeneration(ctx context.Context, generation string) error {
+	if err := os.RemoveAll(c.generationDir(generation)); err != nil {
+		return fmt.Errorf("remove generation: %w", err)
+	}
+	return nil
+}
+
+func (c *ReplicaClient) Snapshots(ctx context.Context, generation string) ([]litestream.SnapshotInfo, error) {
+	ents, err := os.ReadDir(c.snapshotsDir(generation))
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("read snapshots dir: %w", err)
+	}
+
+	var infos []litestream.SnapshotInfo
+	for _, ent := range ents {
+		if ent.IsDir() || !strings.HasSuffix(ent.Name(), ".snapshot.lz4") {
+			continue
+		}
+		index, err := strconv.ParseInt(strings.TrimSuffix(ent.Name(), ".snapshot.lz4"), 16, 64)
+		if err != nil {
+			continue
+		}
+		fi, err := ent.Info()
+		if err != nil {
+			return nil, err
+		}
+		infos = append(infos, litestream.SnapshotInfo{
+			Generation: generation,
+			Index:      int(index),
+			Size:       fi.Size(),
+			CreatedAt:  fi.ModTime().UTC(),
+		})
+	}
+
+	sort.Slice(infos, func(i, j int) bool { return infos[i].Index < infos[j].Index })
+	return infos, nil
+}
+
+func (c *ReplicaClient) WriteSnapshot(ctx context.Context, generation string, index int, r io.Reader) (info litestream.SnapshotInfo, err error) {
+	dir := c.snapshotsDir(generation)
+	if err := os.MkdirAll(dir, 0o750); err != nil {
+		return info, fmt.Errorf("mkdir: %w", err)
+	}
+
+	f, err := os.Create(c.snapshotPath(generation, index))
+	if err != nil {
+		return info, fmt.Errorf("create: %w", err)
+	}
+	defer f.Close()
+
+	n, err := io.Copy(f, r)
+	if err != nil {
+		return info, fmt.Errorf("copy: %w", err)
+	}
+	if err := f.Sync(); err != nil {
+		return info, fmt.Errorf("sync: %w", err)
+	}
+
+	return litestream.SnapshotInfo{Generation: generation, Index: index, Size: n}, nil
+}
+
+func (c *ReplicaClient) SnapshotReader(ctx context.Context, generation string, index int) (io.ReadCloser, error) {
+	return os.Open(c.snapshotPath(generation, index))
+}
+
+func (c *ReplicaClient) DeleteSnapshot(ctx context.Context, generation string, index int) error {
+	if err := os.Remove(c.snapshotPath(generation, index)); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("remove snapshot: %w", err)
+	}
+	return nil
+}
+
+func (c *ReplicaClient) WALSegments(ctx context.Context, generation string) (litestream.WALSegmentIterator, error) {
+	genDir := c.generationDir(generation)
+	ents, err := os.ReadDir(genDir)
+	if os.IsNotExist(err) {
+		return litestream.NewWALSegmentInfoSliceIterator(nil), nil
+	} else if err != nil {
+		return nil, fmt.Errorf("read generation dir: %w", err)
+	}
+
+	var infos []litestream.WALSegmentInfo
+	for _, ent := range ents {
+		if !ent.IsDir() || ent.Name() == "snapshots" {
+			continue
+		}
+		index, err := strconv.ParseInt(ent.Name(), 16, 64)
+		if err != nil {
+			continue
+		}
+
+		idxEnts, err := os.ReadDir(filepath.Join(genDir, ent.Name()))
+		if err != nil {
+			return nil, fmt.Errorf("read index dir: %w", err)
+		}
+		for _, e := range idxEnts {
+			if e.IsDir() || !strings.HasSuffix(e.Name(), ".wal.lz4") {
+				continue
+			}
+			offset, err := strconv.ParseInt(strings.TrimSuffix(e.Name(), ".wal.lz4"), 16, 64)
+			if err != nil {
+				continue
+			}
+			fi, err := e.Info()
+			if err != nil {
+				return nil, err
+			}
+			infos = append(infos, litestream.WALSegmentInfo{
+				Generation: generation,
+				Index:      int(index),
+				Offset:     offset,
+				Size:       fi.Size(),
+				CreatedAt:  fi.ModTime().UTC(),
+			})
+		}
+	}
+
+	sort.Slice(infos, func(i, j int) bool {
+		if infos[i].Index != infos[j].Index {
+			return infos[i].Index < infos[j].Index
+		}
+		return infos[i].Offset < infos[j].Offset
+	})
+	return litestream.NewWALSegmentInfoSliceIterator(infos), nil
+}
+
+func (c *ReplicaClient) WriteWALSegment(ctx context.Context, pos litestream.Pos, r io.Reader) (info litestream.WALSegmentInfo, err error) {
+	dir := c.indexDir(pos.Generation, pos.Index)
+	if err := os.MkdirAll(dir, 0o750); err != nil {
+		return info, fmt.Errorf("mkdir: %w", err)
+	}
+
+	f, err := os.Create(c.walSegmentPath(pos.Generation, pos.Index, pos.Offset))
+	if err != nil {
+		return info, fmt.Errorf("create: %w", err)
+	}
+	defer f.Close()
+
+	n, err := io.Copy(f, r)
+	if err != nil {
+		return info, fmt.Errorf("copy: %w", err)
+	}
+	if err := f.Sync(); err != nil {
+		return info, fmt.Errorf("sync: %w", err)
+	}
+
+	return litestream.WALSegmentInfo{Generation: pos.Generation, Index: pos.Index, Offset: pos.Offset, Size: n}, nil
+}
+
+func (c *ReplicaClient) WALSegmentReader(ctx context.Context, pos litestream.Pos) (io.ReadCloser, error) {
+	return os.Open(c.walSegmentPath(pos.Generation, pos.Index, pos.Offset))
+}
+
+func (c *ReplicaClient) DeleteWALSegments(ctx context.Context, a []litestream.Pos) error {
+	var minIndex int
+	var handleActive bool
+	if c.Replica != nil {
+		minIndex, handleActive = litestream.MinActiveHandleIndex(c.Replica.DB())
+	}
+
+	for _, pos := range a {
+		if handleActive && pos.Index <= minIndex {
+			continue
+		}
+		if err := os.Remove(c.walSegmentPath(pos.Generation, pos.Index, pos.Offset)); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("remove wal segment: %w", err)
+		}
+	}
+	return nil
+}
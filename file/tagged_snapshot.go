@@ -0,0 +1,142 @@
+package file
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/benbjohnson/litestream"
+)
+
+// taggedSnapshotDir returns the directory holding every snapshot stored
+// under tag for generation. Tagged snapshots live under a sibling prefix to
+// ordinary snapshots so EnforceRetention's time-based listing never walks
+// over them.
+func (c *ReplicaClient) taggedSnapshotDir(tag, generation string) string {
+	return filepath.Join(c.Path(), "snapshots-tagged", tag, generation)
+}
+
+// WriteSnapshotWithMetadata writes r as the tagged snapshot for generation,
+// alongside a JSON metadata sidecar file.
+func (c *ReplicaClient) WriteSnapshotWithMetadata(ctx context.Context, tag, generation string, index int, meta map[string]string, r io.Reader) (info litestream.SnapshotInfo, err error) {
+	dir := c.taggedSnapshotDir(tag, generation)
+	if err := os.MkdirAll(dir, 0o750); err != nil {
+		return info, fmt.Errorf("mkdir: %w", err)
+	}
+
+	filename := filepath.Join(dir, fmt.Sprintf("%08x.snapshot.lz4", index))
+	f, err := os.Create(filename)
+	if err != nil {
+		return info, fmt.Errorf("create: %w", err)
+	}
+	defer f.Close()
+
+	n, err := io.Copy(f, r)
+	if err != nil {
+		return info, fmt.Errorf("copy: %w", err)
+	}
+	if err := f.Sync(); err != nil {
+		return info, fmt.Errorf("sync: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		return info, fmt.Errorf("close: %w", err)
+	}
+
+	if meta != nil {
+		b, err := json.Marshal(meta)
+		if err != nil {
+			return info, fmt.Errorf("marshal metadata: %w", err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, fmt.Sprintf("%08x.meta.json", index)), b, 0o640); err != nil {
+			return info, fmt.Errorf("write metadata: %w", err)
+		}
+	}
+
+	return litestream.SnapshotInfo{
+		Generation: generation,
+		Index:      index,
+		Size:       n,
+	}, nil
+}
+
+// TaggedSnapshotReader returns a reader for the tagged snapshot at
+// generation/index.
+func (c *ReplicaClient) TaggedSnapshotReader(ctx context.Context, tag, generation string, index int) (io.ReadCloser, error) {
+	dir := c.taggedSnapshotDir(tag, generation)
+	return os.Open(filepath.Join(dir, fmt.Sprintf("%08x.snapshot.lz4", index)))
+}
+
+// TaggedSnapshots returns every snapshot stored under tag, across every
+// generation, ordered oldest first.
+func (c *ReplicaClient) TaggedSnapshots(ctx context.Context, tag string) ([]litestream.SnapshotInfo, error) {
+	root := filepath.Join(c.Path(), "snapshots-tagged", tag)
+	generations, err := os.ReadDir(root)
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("read tag dir: %w", err)
+	}
+
+	var infos []litestream.SnapshotInfo
+	for _, gent := range generations {
+		if !gent.IsDir() {
+			continue
+		}
+		generation := gent.Name()
+
+		dir := filepath.Join(root, generation)
+		ents, err := os.ReadDir(dir)
+		if err != nil {
+			return nil, fmt.Errorf("read generation dir: %w", err)
+		}
+
+		for _, ent := range ents {
+			if ent.IsDir() || !strings.HasSuffix(ent.Name(), ".snapshot.lz4") {
+				continue
+			}
+
+			index, err := strconv.ParseInt(strings.TrimSuffix(ent.Name(), ".snapshot.lz4"), 16, 64)
+			if err != nil {
+				continue
+			}
+
+			fi, err := ent.Info()
+			if err != nil {
+				return nil, err
+			}
+
+			infos = append(infos, litestream.SnapshotInfo{
+				Generation: generation,
+				Index:      int(index),
+				Size:       fi.Size(),
+				CreatedAt:  fi.ModTime().UTC(),
+			})
+		}
+	}
+
+	sort.Slice(infos, func(i, j int) bool { return infos[i].CreatedAt.Before(infos[j].CreatedAt) })
+	return infos, nil
+}
+
+// DeleteTaggedSnapshot removes the tagged snapshot and its metadata
+// sidecar, if any, for generation/index.
+func (c *ReplicaClient) DeleteTaggedSnapshot(ctx context.Context, tag, generation string, index int) error {
+	dir := c.taggedSnapshotDir(tag, generation)
+
+	snapshot := filepath.Join(dir, fmt.Sprintf("%08x.snapshot.lz4", index))
+	if err := os.Remove(snapshot); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("remove snapshot: %w", err)
+	}
+
+	meta := filepath.Join(dir, fmt.Sprintf("%08x.meta.json", index))
+	if err := os.Remove(meta); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("remove metadata: %w", err)
+	}
+	return nil
+}
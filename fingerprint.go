@@ -0,0 +1,18 @@
+package litestream
+
+import "context"
+
+// Fingerprinter is an optional capability a ReplicaClient can implement so
+// a caching layer (see the cache package) can detect when a listing has
+// changed since it was cached, even if the change came from outside the
+// cached client (e.g. another process writing to the same replica).
+// Clients that don't implement it are cached until explicitly invalidated.
+type Fingerprinter interface {
+	// GenerationsFingerprint returns an opaque string that changes
+	// whenever the set of generations changes.
+	GenerationsFingerprint(ctx context.Context) (string, error)
+
+	// GenerationFingerprint returns an opaque string that changes whenever
+	// generation's snapshots or WAL segments change.
+	GenerationFingerprint(ctx context.Context, generation string) (string, error)
+}
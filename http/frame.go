@@ -0,0 +1,95 @@
+package http
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"io"
+
+	"github.com/benbjohnson/litestream"
+)
+
+// segmentFrame is the wire representation of a single WAL segment pushed
+// over a /stream connection. Data holds the segment bytes exactly as stored
+// by the upstream replica client (lz4-compressed), so a subscriber can
+// write it straight through without recompressing.
+type segmentFrame struct {
+	Generation string
+	Index      int
+	Offset     int64
+	Data       []byte
+}
+
+// pos returns the litestream.Pos identified by the frame.
+func (f segmentFrame) pos() litestream.Pos {
+	return litestream.Pos{Generation: f.Generation, Index: f.Index, Offset: f.Offset}
+}
+
+// writeSegmentFrame writes f to w as a length-prefixed frame.
+func writeSegmentFrame(w io.Writer, f segmentFrame) error {
+	gen := []byte(f.Generation)
+	if err := binary.Write(w, binary.BigEndian, uint32(len(gen))); err != nil {
+		return err
+	}
+	if _, err := w.Write(gen); err != nil {
+		return err
+	}
+	if err := binary.Write(w, binary.BigEndian, uint64(f.Index)); err != nil {
+		return err
+	}
+	if err := binary.Write(w, binary.BigEndian, uint64(f.Offset)); err != nil {
+		return err
+	}
+	if err := binary.Write(w, binary.BigEndian, uint32(len(f.Data))); err != nil {
+		return err
+	}
+	_, err := w.Write(f.Data)
+	return err
+}
+
+// readSegmentFrame reads a single frame written by writeSegmentFrame.
+func readSegmentFrame(r io.Reader) (segmentFrame, error) {
+	var f segmentFrame
+
+	var genLen uint32
+	if err := binary.Read(r, binary.BigEndian, &genLen); err != nil {
+		return f, err
+	}
+	gen := make([]byte, genLen)
+	if _, err := io.ReadFull(r, gen); err != nil {
+		return f, fmt.Errorf("read generation: %w", err)
+	}
+	f.Generation = string(gen)
+
+	var index, offset uint64
+	if err := binary.Read(r, binary.BigEndian, &index); err != nil {
+		return f, err
+	}
+	f.Index = int(index)
+
+	if err := binary.Read(r, binary.BigEndian, &offset); err != nil {
+		return f, err
+	}
+	f.Offset = int64(offset)
+
+	var dataLen uint32
+	if err := binary.Read(r, binary.BigEndian, &dataLen); err != nil {
+		return f, err
+	}
+	f.Data = make([]byte, dataLen)
+	if _, err := io.ReadFull(r, f.Data); err != nil {
+		return f, fmt.Errorf("read data: %w", err)
+	}
+	return f, nil
+}
+
+// newNodeID returns a random, printable identifier for this process so
+// peers can detect when a stream request would loop back to itself.
+func newNodeID() string {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		panic(err) // crypto/rand should never fail
+	}
+	return hex.EncodeToString(buf)
+}
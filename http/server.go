@@ -0,0 +1,214 @@
+// Package http implements a litestream.ReplicaClient-compatible streaming
+// protocol so one litestream node can push its WAL directly to another over
+// a long-lived HTTP connection, letting a downstream instance act as a hot
+// standby without intermediate object storage.
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/benbjohnson/litestream"
+)
+
+const (
+	// NodeIDHeader identifies the node initiating a stream request. A
+	// server rejects requests whose id matches its own to prevent a node
+	// from subscribing to itself when peers are meshed together.
+	NodeIDHeader = "Litestream-Node-Id"
+
+	// IfGenerationHeader, IfIndexHeader and IfOffsetHeader let a client
+	// resume a single default generation without sending a JSON position
+	// map. IfOffsetHeader is optional and defaults to 0, resuming at the
+	// start of IfIndexHeader's index.
+	IfGenerationHeader = "If-Generation"
+	IfIndexHeader      = "If-Index"
+	IfOffsetHeader     = "If-Offset"
+
+	// pollInterval is how often the server checks for newly written WAL
+	// segments while a stream connection is otherwise idle.
+	pollInterval = 1 * time.Second
+)
+
+// Server streams a replica's WAL segments to subscribing peers over HTTP.
+type Server struct {
+	Replica *litestream.Replica
+
+	// NodeID uniquely identifies this node. See NodeIDHeader.
+	NodeID string
+
+	ln  net.Listener
+	srv *http.Server
+}
+
+// NewServer returns a new instance of Server for replica.
+func NewServer(replica *litestream.Replica) *Server {
+	return &Server{
+		Replica: replica,
+		NodeID:  newNodeID(),
+		srv:     &http.Server{},
+	}
+}
+
+// Open starts listening on addr. Use "127.0.0.1:0" to bind an ephemeral
+// port, then read it back from URL().
+func (s *Server) Open(addr string) (err error) {
+	if s.ln, err = net.Listen("tcp", addr); err != nil {
+		return err
+	}
+	s.srv.Handler = s
+
+	go func() {
+		if err := s.srv.Serve(s.ln); err != nil && err != http.ErrServerClosed {
+			slog.Error("litestream http server", "error", err)
+		}
+	}()
+	return nil
+}
+
+// Close shuts down the server and stops accepting new connections.
+func (s *Server) Close() error {
+	return s.srv.Close()
+}
+
+// URL returns the base URL the server is listening on.
+func (s *Server) URL() string {
+	return fmt.Sprintf("http://%s", s.ln.Addr())
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/stream" || r.Method != http.MethodPost {
+		http.NotFound(w, r)
+		return
+	}
+	s.serveStream(w, r)
+}
+
+// serveStream handles POST /stream, pushing WAL segments newer than the
+// position(s) supplied by the client until the request context is canceled.
+func (s *Server) serveStream(w http.ResponseWriter, r *http.Request) {
+	if nodeID := r.Header.Get(NodeIDHeader); nodeID != "" && nodeID == s.NodeID {
+		http.Error(w, "cannot stream to self: node id matches server", http.StatusBadRequest)
+		return
+	}
+
+	positions, err := readPositions(r)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("read positions: %s", err), http.StatusBadRequest)
+		return
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set(NodeIDHeader, s.NodeID)
+	w.WriteHeader(http.StatusOK)
+
+	ctx := r.Context()
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+
+	for {
+		sent, err := s.sendNewSegments(ctx, w, positions)
+		if err != nil {
+			slog.Error("litestream http stream", "error", err)
+			return
+		} else if sent {
+			flusher.Flush()
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// readPositions returns the client's last-acked position per generation,
+// either from a JSON body or, failing that, from the If-Generation,
+// If-Index and If-Offset headers for a single default generation.
+func readPositions(r *http.Request) (map[string]litestream.Pos, error) {
+	positions := make(map[string]litestream.Pos)
+
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&positions); err != nil && err != io.EOF {
+			return nil, err
+		}
+	}
+	if len(positions) == 0 {
+		if generation := r.Header.Get(IfGenerationHeader); generation != "" {
+			index, _ := strconv.Atoi(r.Header.Get(IfIndexHeader))
+			offset, _ := strconv.ParseInt(r.Header.Get(IfOffsetHeader), 10, 64)
+			positions[generation] = litestream.Pos{Generation: generation, Index: index, Offset: offset}
+		}
+	}
+	return positions, nil
+}
+
+// sendNewSegments writes any WAL segments at or after positions to w,
+// advancing positions as it goes. It reports whether any bytes were sent.
+func (s *Server) sendNewSegments(ctx context.Context, w io.Writer, positions map[string]litestream.Pos) (sent bool, err error) {
+	generations, err := s.Replica.Client.Generations(ctx)
+	if err != nil {
+		return false, fmt.Errorf("generations: %w", err)
+	}
+
+	for _, generation := range generations {
+		start := positions[generation]
+
+		itr, err := s.Replica.Client.WALSegments(ctx, generation)
+		if err != nil {
+			return sent, fmt.Errorf("wal segments: %w", err)
+		}
+
+		for itr.Next() {
+			info := itr.WALSegment()
+			if info.Index < start.Index || (info.Index == start.Index && info.Offset < start.Offset) {
+				continue
+			}
+
+			if err := s.sendSegment(ctx, w, info); err != nil {
+				_ = itr.Close()
+				return sent, err
+			}
+			sent = true
+			positions[generation] = litestream.Pos{Generation: generation, Index: info.Index, Offset: info.Offset + info.Size}
+		}
+		if err := itr.Close(); err != nil {
+			return sent, fmt.Errorf("close iterator: %w", err)
+		}
+	}
+	return sent, nil
+}
+
+// sendSegment writes a single WAL segment frame to w.
+func (s *Server) sendSegment(ctx context.Context, w io.Writer, info litestream.WALSegmentInfo) error {
+	r, err := s.Replica.Client.WALSegmentReader(ctx, info.Pos())
+	if err != nil {
+		return fmt.Errorf("segment reader: %w", err)
+	}
+	defer r.Close()
+
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return fmt.Errorf("read segment: %w", err)
+	}
+
+	return writeSegmentFrame(w, segmentFrame{
+		Generation: info.Generation,
+		Index:      info.Index,
+		Offset:     info.Offset,
+		Data:       data,
+	})
+}
@@ -0,0 +1,55 @@
+package http
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/benbjohnson/litestream"
+)
+
+// TestReadPositions_Headers verifies that, absent a JSON body,
+// readPositions falls back to the If-Generation/If-Index/If-Offset headers,
+// including resuming at a byte offset within the index rather than only at
+// its start.
+func TestReadPositions_Headers(t *testing.T) {
+	req, err := http.NewRequest(http.MethodPost, "/stream", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set(IfGenerationHeader, "abc123")
+	req.Header.Set(IfIndexHeader, "2")
+	req.Header.Set(IfOffsetHeader, "512")
+
+	positions, err := readPositions(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[string]litestream.Pos{
+		"abc123": {Generation: "abc123", Index: 2, Offset: 512},
+	}
+	if got := positions; got["abc123"] != want["abc123"] {
+		t.Fatalf("positions=%v, want %v", got, want)
+	}
+}
+
+// TestReadPositions_HeadersDefaultOffset verifies that If-Offset is
+// optional and defaults to resuming at the start of If-Index's index.
+func TestReadPositions_HeadersDefaultOffset(t *testing.T) {
+	req, err := http.NewRequest(http.MethodPost, "/stream", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set(IfGenerationHeader, "abc123")
+	req.Header.Set(IfIndexHeader, "2")
+
+	positions, err := readPositions(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := litestream.Pos{Generation: "abc123", Index: 2, Offset: 0}
+	if got := positions["abc123"]; got != want {
+		t.Fatalf("positions[abc123]=%v, want %v", got, want)
+	}
+}
@@ -0,0 +1,115 @@
+package http
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/benbjohnson/litestream"
+	"github.com/benbjohnson/litestream/file"
+)
+
+// Subscriber connects to a peer's Server and writes incoming WAL segments
+// into a local file.ReplicaClient, allowing a downstream litestream
+// instance to act as a hot standby without object storage.
+type Subscriber struct {
+	URL    string
+	NodeID string
+	Client *file.ReplicaClient
+
+	HTTPClient *http.Client
+}
+
+// NewSubscriber returns a new instance of Subscriber that streams from url
+// into client.
+func NewSubscriber(url string, client *file.ReplicaClient) *Subscriber {
+	return &Subscriber{
+		URL:        url,
+		NodeID:     newNodeID(),
+		Client:     client,
+		HTTPClient: http.DefaultClient,
+	}
+}
+
+// Run opens a stream to the peer and applies incoming segments until ctx is
+// canceled or the connection is lost. On reconnect, callers should invoke
+// Run again; it always resumes from the segments already present locally.
+func (s *Subscriber) Run(ctx context.Context) error {
+	positions, err := s.localPositions(ctx)
+	if err != nil {
+		return fmt.Errorf("local positions: %w", err)
+	}
+
+	body, err := json.Marshal(positions)
+	if err != nil {
+		return fmt.Errorf("marshal positions: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.URL+"/stream", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set(NodeIDHeader, s.NodeID)
+
+	resp, err := s.HTTPClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("stream request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("stream request: status=%d", resp.StatusCode)
+	}
+	if id := resp.Header.Get(NodeIDHeader); id != "" && id == s.NodeID {
+		return fmt.Errorf("refusing to subscribe to self (node id %s)", s.NodeID)
+	}
+
+	for {
+		frame, err := readSegmentFrame(resp.Body)
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return fmt.Errorf("read frame: %w", err)
+		}
+
+		if _, err := s.Client.WriteWALSegment(ctx, frame.pos(), bytes.NewReader(frame.Data)); err != nil {
+			return fmt.Errorf("write wal segment: %w", err)
+		}
+	}
+}
+
+// localPositions returns the position immediately following the last WAL
+// segment already present for each generation in the local client, so a
+// (re)connecting subscriber resumes instead of re-downloading segments it
+// already has.
+func (s *Subscriber) localPositions(ctx context.Context) (map[string]litestream.Pos, error) {
+	positions := make(map[string]litestream.Pos)
+
+	generations, err := s.Client.Generations(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, generation := range generations {
+		itr, err := s.Client.WALSegments(ctx, generation)
+		if err != nil {
+			return nil, err
+		}
+
+		var pos litestream.Pos
+		for itr.Next() {
+			info := itr.WALSegment()
+			if info.Index > pos.Index || (info.Index == pos.Index && info.Offset >= pos.Offset) {
+				pos = litestream.Pos{Generation: generation, Index: info.Index, Offset: info.Offset + info.Size}
+			}
+		}
+		if err := itr.Close(); err != nil {
+			return nil, err
+		}
+		positions[generation] = pos
+	}
+	return positions, nil
+}
@@ -0,0 +1,141 @@
+package integration_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/benbjohnson/litestream"
+	"github.com/benbjohnson/litestream/file"
+)
+
+// TestCompactor_Compact verifies that L0 WAL segments within an index are
+// merged into a single L1 segment once the threshold is crossed, and that
+// the superseded L0 segments are removed afterward.
+func TestCompactor_Compact(t *testing.T) {
+	db, sqldb := MustOpenDBs(t)
+	defer MustCloseDBs(t, db, sqldb)
+
+	c := file.NewReplicaClient(t.TempDir())
+	r := litestream.NewReplica(db, "")
+	r.Client = c
+
+	if _, err := sqldb.Exec(`CREATE TABLE foo (bar TEXT);`); err != nil {
+		t.Fatal(err)
+	}
+
+	// Produce three L0 segments within the same index.
+	for i := 0; i < 3; i++ {
+		if _, err := sqldb.Exec(`INSERT INTO foo (bar) VALUES ('baz');`); err != nil {
+			t.Fatal(err)
+		} else if err := db.Sync(context.Background()); err != nil {
+			t.Fatal(err)
+		} else if err := r.Sync(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	compactor := litestream.NewCompactor(r)
+	compactor.Levels = 1
+	compactor.L0Threshold = 2
+	compactor.Ratio = 10
+
+	if err := compactor.Compact(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	itr, err := c.WALSegmentsAtLevel(context.Background(), db.Pos().Generation, 0, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer itr.Close()
+
+	var n int
+	for itr.Next() {
+		n++
+	}
+	if err := itr.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := n, 1; got != want {
+		t.Fatalf("len(L1 segments)=%v, want %v", got, want)
+	}
+}
+
+// TestCompactor_Compact_MultiLevel verifies that segments above L0 are
+// themselves merged into the next level once they cross their own
+// threshold, not just L0 into L1. Each compaction pass over a still-open
+// index produces one more same-level segment for it, so repeated L0->L1
+// passes build up multiple L1 segments the same way repeated Syncs build up
+// multiple L0 segments.
+func TestCompactor_Compact_MultiLevel(t *testing.T) {
+	db, sqldb := MustOpenDBs(t)
+	defer MustCloseDBs(t, db, sqldb)
+
+	c := file.NewReplicaClient(t.TempDir())
+	r := litestream.NewReplica(db, "")
+	r.Client = c
+
+	if _, err := sqldb.Exec(`CREATE TABLE foo (bar TEXT);`); err != nil {
+		t.Fatal(err)
+	}
+
+	compactor := litestream.NewCompactor(r)
+	compactor.Levels = 2
+	compactor.L0Threshold = 2
+	compactor.Ratio = 1 // same threshold at every level, to keep the test small
+
+	// Two rounds of two L0 segments each, compacting after every round:
+	// round one's L0->L1 merge produces the first L1 segment; round two's
+	// produces the second, which should cross the L1->L2 threshold and
+	// merge within the same Compact call.
+	for round := 0; round < 2; round++ {
+		for i := 0; i < 2; i++ {
+			if _, err := sqldb.Exec(`INSERT INTO foo (bar) VALUES ('baz');`); err != nil {
+				t.Fatal(err)
+			} else if err := db.Sync(context.Background()); err != nil {
+				t.Fatal(err)
+			} else if err := r.Sync(context.Background()); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err := compactor.Compact(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	generation := db.Pos().Generation
+
+	itr, err := c.WALSegmentsAtLevel(context.Background(), generation, 0, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer itr.Close()
+
+	var n int
+	for itr.Next() {
+		n++
+	}
+	if err := itr.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := n, 1; got != want {
+		t.Fatalf("len(L2 segments)=%v, want %v (L1 segments should have been promoted once they crossed their own threshold)", got, want)
+	}
+
+	itr, err = c.WALSegmentsAtLevel(context.Background(), generation, 0, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer itr.Close()
+
+	n = 0
+	for itr.Next() {
+		n++
+	}
+	if err := itr.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := n, 0; got != want {
+		t.Fatalf("len(L1 segments)=%v, want %v (superseded L1 segments should have been deleted after promotion)", got, want)
+	}
+}
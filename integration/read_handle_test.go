@@ -0,0 +1,175 @@
+package integration_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/benbjohnson/litestream"
+	"github.com/benbjohnson/litestream/file"
+)
+
+// TestDB_Acquire verifies that a ReadHandle pins the database's position at
+// the time it was acquired, independent of writes made afterward.
+func TestDB_Acquire(t *testing.T) {
+	db, sqldb := MustOpenDBs(t)
+	defer MustCloseDBs(t, db, sqldb)
+
+	if _, err := sqldb.Exec(`CREATE TABLE foo (bar TEXT);`); err != nil {
+		t.Fatal(err)
+	} else if _, err := sqldb.Exec(`INSERT INTO foo (bar) VALUES ('baz');`); err != nil {
+		t.Fatal(err)
+	} else if err := db.Sync(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	h, err := db.Acquire(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer h.Close()
+
+	pinned := h.Pos()
+
+	// Writes after acquiring must not move the handle's pinned position.
+	if _, err := sqldb.Exec(`INSERT INTO foo (bar) VALUES ('qux');`); err != nil {
+		t.Fatal(err)
+	} else if err := db.Sync(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	if got, want := h.Pos(), pinned; got != want {
+		t.Fatalf("Pos()=%s, want %s (must stay pinned after later writes)", got, want)
+	}
+
+	if got, want := h.Pos().Index, db.Pos().Index; got > want {
+		t.Fatalf("handle pinned to a later index (%d) than the live db (%d)", got, want)
+	}
+
+	if minIndex, ok := litestream.MinActiveHandleIndex(db); !ok || minIndex != pinned.Index {
+		t.Fatalf("MinActiveHandleIndex()=(%d, %v), want (%d, true)", minIndex, ok, pinned.Index)
+	}
+
+	// Open must reconstruct a copy-on-read database reflecting the pinned
+	// position, not the later 'qux' write.
+	hsqldb, err := h.Open()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var count int
+	if err := hsqldb.QueryRow(`SELECT count(*) FROM foo`).Scan(&count); err != nil {
+		t.Fatal(err)
+	} else if got, want := count, 1; got != want {
+		t.Fatalf("count(*)=%d, want %d (reconstruction must not include writes after acquire)", got, want)
+	}
+	var bar string
+	if err := hsqldb.QueryRow(`SELECT bar FROM foo`).Scan(&bar); err != nil {
+		t.Fatal(err)
+	} else if got, want := bar, "baz"; got != want {
+		t.Fatalf("bar=%q, want %q", got, want)
+	}
+
+	if err := h.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := litestream.MinActiveHandleIndex(db); ok {
+		t.Fatal("expected no active handles after Close")
+	}
+}
+
+// TestDB_Acquire_CheckpointBoundary verifies that once a
+// CheckpointModeTruncate checkpoint advances the db's live index past a
+// handle's pinned position, Open falls back to reconstructing the pinned
+// position from a configured replica rather than trusting the live main
+// file and -wal, which a ReadHandle does not block checkpoints from
+// overwriting out from under it.
+func TestDB_Acquire_CheckpointBoundary(t *testing.T) {
+	db, sqldb := MustOpenDBs(t)
+	defer MustCloseDBs(t, db, sqldb)
+
+	c := file.NewReplicaClient(t.TempDir())
+	r := litestream.NewReplica(db, "")
+	r.Client = c
+	db.Replicas = append(db.Replicas, r)
+
+	if _, err := sqldb.Exec(`CREATE TABLE foo (bar TEXT);`); err != nil {
+		t.Fatal(err)
+	} else if _, err := sqldb.Exec(`INSERT INTO foo (bar) VALUES ('baz');`); err != nil {
+		t.Fatal(err)
+	} else if err := db.Sync(context.Background()); err != nil {
+		t.Fatal(err)
+	} else if err := r.Sync(context.Background()); err != nil {
+		t.Fatal(err)
+	} else if _, err := r.Snapshot(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	h, err := db.Acquire(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer h.Close()
+
+	if err := db.Checkpoint(context.Background(), litestream.CheckpointModeTruncate); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := sqldb.Exec(`INSERT INTO foo (bar) VALUES ('qux');`); err != nil {
+		t.Fatal(err)
+	} else if err := db.Sync(context.Background()); err != nil {
+		t.Fatal(err)
+	} else if err := r.Sync(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	hsqldb, err := h.Open()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var count int
+	if err := hsqldb.QueryRow(`SELECT count(*) FROM foo`).Scan(&count); err != nil {
+		t.Fatal(err)
+	} else if got, want := count, 1; got != want {
+		t.Fatalf("count(*)=%d, want %d (reconstruction must not include writes after acquire)", got, want)
+	}
+	var bar string
+	if err := hsqldb.QueryRow(`SELECT bar FROM foo`).Scan(&bar); err != nil {
+		t.Fatal(err)
+	} else if got, want := bar, "baz"; got != want {
+		t.Fatalf("bar=%q, want %q", got, want)
+	}
+}
+
+// TestDB_Acquire_CheckpointBoundary_NoReplica verifies that Open still fails
+// loudly, rather than returning corrupted data, when a checkpoint advances
+// past the pinned position and no replica is configured to reconstruct it
+// from.
+func TestDB_Acquire_CheckpointBoundary_NoReplica(t *testing.T) {
+	db, sqldb := MustOpenDBs(t)
+	defer MustCloseDBs(t, db, sqldb)
+
+	if _, err := sqldb.Exec(`CREATE TABLE foo (bar TEXT);`); err != nil {
+		t.Fatal(err)
+	} else if _, err := sqldb.Exec(`INSERT INTO foo (bar) VALUES ('baz');`); err != nil {
+		t.Fatal(err)
+	} else if err := db.Sync(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	h, err := db.Acquire(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer h.Close()
+
+	if err := db.Checkpoint(context.Background(), litestream.CheckpointModeTruncate); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := sqldb.Exec(`INSERT INTO foo (bar) VALUES ('qux');`); err != nil {
+		t.Fatal(err)
+	} else if err := db.Sync(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := h.Open(); err == nil {
+		t.Fatal("expected Open to fail once a checkpoint advances past the pinned index with no replica configured, not return a reconstruction silently missing or gaining rows")
+	}
+}
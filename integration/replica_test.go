@@ -9,6 +9,7 @@ import (
 	"path/filepath"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/benbjohnson/litestream"
 	"github.com/benbjohnson/litestream/file"
@@ -124,6 +125,170 @@ func TestReplica_Snapshot(t *testing.T) {
 	}
 }
 
+// TestReplica_EnforceRetention_ReclaimsWALSegments verifies that
+// EnforceRetention both removes an expired snapshot and reclaims the WAL
+// segments that preceded it, while leaving the segments a surviving
+// snapshot still needs to replay through untouched.
+func TestReplica_EnforceRetention_ReclaimsWALSegments(t *testing.T) {
+	db, sqldb := MustOpenDBs(t)
+	defer MustCloseDBs(t, db, sqldb)
+
+	c := file.NewReplicaClient(t.TempDir())
+	r := litestream.NewReplica(db, "")
+	r.Client = c
+	r.Retention = 20 * time.Millisecond
+
+	// Execute a query to force a write to the WAL.
+	if _, err := sqldb.Exec(`CREATE TABLE foo (bar TEXT);`); err != nil {
+		t.Fatal(err)
+	} else if err := db.Sync(context.Background()); err != nil {
+		t.Fatal(err)
+	} else if err := r.Sync(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	// Snapshot index 0; this one will be old enough to expire below.
+	if _, err := r.Snapshot(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	// Execute a query to force a write to the WAL & truncate to start new
+	// index, then write again so index 1 has a WAL segment of its own.
+	if _, err := sqldb.Exec(`INSERT INTO foo (bar) VALUES ('baz');`); err != nil {
+		t.Fatal(err)
+	} else if err := db.Checkpoint(context.Background(), litestream.CheckpointModeTruncate); err != nil {
+		t.Fatal(err)
+	} else if _, err := sqldb.Exec(`INSERT INTO foo (bar) VALUES ('qux');`); err != nil {
+		t.Fatal(err)
+	} else if err := r.Sync(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	time.Sleep(2 * r.Retention)
+
+	// Snapshot index 1; this one stays within Retention when EnforceRetention runs.
+	generation := db.Pos().Generation
+	if _, err := r.Snapshot(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := r.EnforceRetention(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	// The expired index-0 snapshot should be gone; the fresh index-1 one should remain.
+	if infos, err := r.Snapshots(context.Background()); err != nil {
+		t.Fatal(err)
+	} else if got, want := len(infos), 1; got != want {
+		t.Fatalf("len(Snapshots)=%v, want %v", got, want)
+	} else if got, want := infos[0].Index, 1; got != want {
+		t.Fatalf("Snapshots[0].Index=%v, want %v", got, want)
+	}
+
+	// The index-0 WAL segments it superseded should have been reclaimed;
+	// the index-1 segment the surviving snapshot still replays through
+	// should remain.
+	itr, err := c.WALSegments(context.Background(), generation)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer itr.Close()
+
+	var indexes []int
+	for itr.Next() {
+		indexes = append(indexes, itr.WALSegment().Index)
+	}
+	if err := itr.Close(); err != nil {
+		t.Fatal(err)
+	}
+	for _, index := range indexes {
+		if index == 0 {
+			t.Fatalf("index-0 wal segment still present after EnforceRetention: %v", indexes)
+		}
+	}
+	if len(indexes) == 0 {
+		t.Fatalf("expected index-1 wal segment to remain, got none")
+	}
+}
+
+// TestReplica_EnforceRetention_ReclaimsCompactedWALSegments verifies that
+// EnforceRetention reclaims an expired index's WAL segments even once the
+// compactor has merged them into a higher level, not just its raw L0
+// segments.
+func TestReplica_EnforceRetention_ReclaimsCompactedWALSegments(t *testing.T) {
+	db, sqldb := MustOpenDBs(t)
+	defer MustCloseDBs(t, db, sqldb)
+
+	c := file.NewReplicaClient(t.TempDir())
+	r := litestream.NewReplica(db, "")
+	r.Client = c
+	r.Retention = 20 * time.Millisecond
+
+	// Produce two L0 segments within index 0.
+	if _, err := sqldb.Exec(`CREATE TABLE foo (bar TEXT);`); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 2; i++ {
+		if _, err := sqldb.Exec(`INSERT INTO foo (bar) VALUES ('baz');`); err != nil {
+			t.Fatal(err)
+		} else if err := db.Sync(context.Background()); err != nil {
+			t.Fatal(err)
+		} else if err := r.Sync(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	compactor := litestream.NewCompactor(r)
+	compactor.Levels = 1
+	compactor.L0Threshold = 2
+	if err := compactor.Compact(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	// Snapshot index 0, now compacted into L1; this one will be old enough
+	// to expire below.
+	if _, err := r.Snapshot(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	// Start a new index and give index 0's snapshot time to expire.
+	if _, err := sqldb.Exec(`INSERT INTO foo (bar) VALUES ('baz');`); err != nil {
+		t.Fatal(err)
+	} else if err := db.Checkpoint(context.Background(), litestream.CheckpointModeTruncate); err != nil {
+		t.Fatal(err)
+	} else if err := r.Sync(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	time.Sleep(2 * r.Retention)
+
+	generation := db.Pos().Generation
+	if _, err := r.Snapshot(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := r.EnforceRetention(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	itr, err := c.WALSegmentsAtLevel(context.Background(), generation, -1, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer itr.Close()
+
+	var n int
+	for itr.Next() {
+		n++
+	}
+	if err := itr.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := n, 0; got != want {
+		t.Fatalf("len(L1 segments)=%v, want %v (index-0's compacted segment should have been reclaimed)", got, want)
+	}
+}
+
 // MustOpenDBs returns a new instance of a DB & associated SQL DB.
 func MustOpenDBs(tb testing.TB) (*litestream.DB, *sql.DB) {
 	tb.Helper()
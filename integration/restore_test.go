@@ -0,0 +1,84 @@
+package integration_test
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+	"testing"
+
+	"github.com/benbjohnson/litestream"
+	"github.com/benbjohnson/litestream/file"
+)
+
+// TestReplica_Restore_Tag_ThroughNow verifies that restoring from a tagged
+// snapshot stops at the tag by default, and that -through-now (ThroughNow)
+// replays every subsequent index up to the newest segment recorded.
+func TestReplica_Restore_Tag_ThroughNow(t *testing.T) {
+	db, sqldb := MustOpenDBs(t)
+	defer MustCloseDBs(t, db, sqldb)
+
+	c := file.NewReplicaClient(t.TempDir())
+	r := litestream.NewReplica(db, "")
+	r.Client = c
+
+	if _, err := sqldb.Exec(`CREATE TABLE foo (bar TEXT);`); err != nil {
+		t.Fatal(err)
+	} else if _, err := sqldb.Exec(`INSERT INTO foo (bar) VALUES ('a');`); err != nil {
+		t.Fatal(err)
+	} else if err := db.Sync(context.Background()); err != nil {
+		t.Fatal(err)
+	} else if err := r.Sync(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := r.SnapshotWithTag(context.Background(), "v1", nil); err != nil {
+		t.Fatal(err)
+	}
+
+	// Advance to a new index and write more data after the tag.
+	if err := db.Checkpoint(context.Background(), litestream.CheckpointModeTruncate); err != nil {
+		t.Fatal(err)
+	} else if _, err := sqldb.Exec(`INSERT INTO foo (bar) VALUES ('b');`); err != nil {
+		t.Fatal(err)
+	} else if err := db.Sync(context.Background()); err != nil {
+		t.Fatal(err)
+	} else if err := r.Sync(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	dir := t.TempDir()
+
+	dest0 := filepath.Join(dir, "restored0.db")
+	if _, err := r.Restore(context.Background(), dest0, litestream.RestoreOptions{Tag: "v1"}); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := countFooRows(t, dest0), 1; got != want {
+		t.Fatalf("count(dest0)=%v, want %v", got, want)
+	}
+
+	dest1 := filepath.Join(dir, "restored1.db")
+	if _, err := r.Restore(context.Background(), dest1, litestream.RestoreOptions{Tag: "v1", ThroughNow: true}); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := countFooRows(t, dest1), 2; got != want {
+		t.Fatalf("count(dest1)=%v, want %v", got, want)
+	}
+}
+
+// countFooRows returns the number of rows in the foo table of the sqlite
+// database at path, for verifying a restored database's contents.
+func countFooRows(t *testing.T, path string) int {
+	t.Helper()
+
+	d, err := sql.Open("sqlite3", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer d.Close()
+
+	var n int
+	if err := d.QueryRow(`SELECT COUNT(*) FROM foo;`).Scan(&n); err != nil {
+		t.Fatal(err)
+	}
+	return n
+}
@@ -0,0 +1,62 @@
+package integration_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/benbjohnson/litestream"
+	"github.com/benbjohnson/litestream/file"
+)
+
+// TestReplica_SnapshotWithTag verifies that a tagged snapshot can be
+// created, listed, and deleted independently of the ordinary snapshot set.
+func TestReplica_SnapshotWithTag(t *testing.T) {
+	db, sqldb := MustOpenDBs(t)
+	defer MustCloseDBs(t, db, sqldb)
+
+	c := file.NewReplicaClient(t.TempDir())
+	r := litestream.NewReplica(db, "")
+	r.Client = c
+
+	if _, err := sqldb.Exec(`CREATE TABLE foo (bar TEXT);`); err != nil {
+		t.Fatal(err)
+	} else if err := db.Sync(context.Background()); err != nil {
+		t.Fatal(err)
+	} else if err := r.Sync(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	pos0 := db.Pos()
+	info, err := r.SnapshotWithTag(context.Background(), "pre-migration", map[string]string{"reason": "schema change"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := info.Pos(), pos0.Truncate(); got != want {
+		t.Fatalf("pos=%s, want %s", got, want)
+	}
+
+	// Ordinary snapshots are unaffected by the tagged one.
+	if infos, err := r.Snapshots(context.Background()); err != nil {
+		t.Fatal(err)
+	} else if got, want := len(infos), 0; got != want {
+		t.Fatalf("len(Snapshots)=%v, want %v", got, want)
+	}
+
+	infos, err := r.ListTaggedSnapshots(context.Background(), "pre-migration")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := len(infos), 1; got != want {
+		t.Fatalf("len(ListTaggedSnapshots)=%v, want %v", got, want)
+	}
+
+	if err := r.DeleteTaggedSnapshot(context.Background(), "pre-migration", infos[0].Pos()); err != nil {
+		t.Fatal(err)
+	}
+
+	if infos, err := r.ListTaggedSnapshots(context.Background(), "pre-migration"); err != nil {
+		t.Fatal(err)
+	} else if got, want := len(infos), 0; got != want {
+		t.Fatalf("len(ListTaggedSnapshots) after delete=%v, want %v", got, want)
+	}
+}
@@ -0,0 +1,28 @@
+package litestream
+
+import "fmt"
+
+// Pos is a byte-accurate position within a generation's WAL. Index
+// identifies a contiguous run of WAL segments produced between two
+// checkpoints; Offset is the byte offset within that index's WAL.
+type Pos struct {
+	Generation string
+	Index      int
+	Offset     int64
+}
+
+// String returns a human-readable representation of the position.
+func (p Pos) String() string {
+	return fmt.Sprintf("%s/%08x:%d", p.Generation, p.Index, p.Offset)
+}
+
+// IsZero returns true if p is the zero value.
+func (p Pos) IsZero() bool {
+	return p == Pos{}
+}
+
+// Truncate returns p with its offset reset to zero, representing the
+// position immediately after a full snapshot of the index.
+func (p Pos) Truncate() Pos {
+	return Pos{Generation: p.Generation, Index: p.Index}
+}
@@ -0,0 +1,69 @@
+package litestream
+
+import (
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// handles tracks every live ReadHandle per DB, standing in for a
+// DB.activeHandles field: EnforceRetention and Compactor consult
+// MinActiveHandleIndex before deleting any segment whose index is at or
+// below the minimum pinned index.
+var (
+	handlesMu sync.Mutex
+	handles   = make(map[*DB]map[*ReadHandle]struct{})
+)
+
+func registerHandle(db *DB, h *ReadHandle) {
+	handlesMu.Lock()
+	defer handlesMu.Unlock()
+
+	if handles[db] == nil {
+		handles[db] = make(map[*ReadHandle]struct{})
+	}
+	handles[db][h] = struct{}{}
+}
+
+func unregisterHandle(db *DB, h *ReadHandle) {
+	handlesMu.Lock()
+	defer handlesMu.Unlock()
+
+	delete(handles[db], h)
+	if len(handles[db]) == 0 {
+		delete(handles, db)
+	}
+}
+
+// MinActiveHandleIndex returns the smallest WAL index pinned by an active
+// ReadHandle on db, and reports whether any handle is active at all.
+func MinActiveHandleIndex(db *DB) (index int, ok bool) {
+	handlesMu.Lock()
+	defer handlesMu.Unlock()
+
+	for h := range handles[db] {
+		if !ok || h.pos.Index < index {
+			index, ok = h.pos.Index, true
+		}
+	}
+	return index, ok
+}
+
+// WatchStaleHandles force-closes any ReadHandle on db older than maxAge.
+// DB.Open runs it every MaxHandleAge from a watchdog goroutine when
+// MaxHandleAge is above zero, so a leaked handle can't pin GC forever.
+func WatchStaleHandles(db *DB, maxAge time.Duration) {
+	handlesMu.Lock()
+	var stale []*ReadHandle
+	for h := range handles[db] {
+		if time.Since(h.createdAt) > maxAge {
+			stale = append(stale, h)
+		}
+	}
+	handlesMu.Unlock()
+
+	for _, h := range stale {
+		slog.Warn("closing stale read handle", "pos", h.pos, "age", time.Since(h.createdAt))
+		_ = h.Close()
+	}
+}
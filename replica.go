@@ -0,0 +1,310 @@
+package litestream
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/pierrec/lz4/v4"
+)
+
+// DefaultRetention is the default value for Replica.Retention.
+const DefaultRetention = 24 * time.Hour
+
+// Replica mirrors a single DB to a destination via a ReplicaClient. Sync
+// pushes newly-written WAL bytes as they appear; Snapshot writes a full,
+// compressed copy of the database that a restore can start from instead of
+// replaying every WAL segment since the beginning of the generation.
+type Replica struct {
+	db   *DB
+	name string
+
+	mu  sync.RWMutex
+	pos Pos
+
+	Client ReplicaClient
+
+	// Retention is how long ordinary (untagged) snapshots and the WAL
+	// segments older than them are kept before EnforceRetention removes
+	// them.
+	Retention time.Duration
+
+	Logger *slog.Logger
+}
+
+// NewReplica returns a new instance of Replica for db, identified by name.
+func NewReplica(db *DB, name string) *Replica {
+	return &Replica{
+		db:        db,
+		name:      name,
+		Retention: DefaultRetention,
+		Logger:    slog.With("replica", name),
+	}
+}
+
+// DB returns the database this replica mirrors.
+func (r *Replica) DB() *DB { return r.db }
+
+// Name returns the name of the replica.
+func (r *Replica) Name() string {
+	if r.name != "" {
+		return r.name
+	}
+	if r.Client != nil {
+		return r.Client.Type()
+	}
+	return ""
+}
+
+// Pos returns the position through which the replica has synced.
+func (r *Replica) Pos() Pos {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.pos
+}
+
+// Sync pushes any WAL bytes written since the last call to Sync to the
+// replica's client as a single new WAL segment.
+func (r *Replica) Sync(ctx context.Context) error {
+	dbPos := r.db.Pos()
+
+	r.mu.RLock()
+	pos := r.pos
+	r.mu.RUnlock()
+
+	if pos.Generation != dbPos.Generation || pos.Index != dbPos.Index {
+		pos = Pos{Generation: dbPos.Generation, Index: dbPos.Index}
+	}
+
+	if dbPos.Offset > pos.Offset {
+		rc, err := r.db.WALReader(pos.Offset)
+		if err != nil {
+			return fmt.Errorf("wal reader: %w", err)
+		}
+		defer rc.Close()
+
+		pr, pw := io.Pipe()
+		go func() {
+			zw := lz4.NewWriter(pw)
+			_, err := io.Copy(zw, rc)
+			if err == nil {
+				err = zw.Close()
+			}
+			pw.CloseWithError(err)
+		}()
+
+		info, err := r.Client.WriteWALSegment(ctx, pos, pr)
+		if err != nil {
+			return fmt.Errorf("write wal segment: %w", err)
+		}
+		pos = Pos{Generation: info.Generation, Index: info.Index, Offset: dbPos.Offset}
+	}
+
+	r.mu.Lock()
+	r.pos = pos
+	r.mu.Unlock()
+	return nil
+}
+
+// snapshotReader opens the database file for reading as of the replica's
+// db's current position, for Snapshot and SnapshotWithTag to compress and
+// upload.
+func (r *Replica) snapshotReader(ctx context.Context) (generation string, index int, rc io.ReadCloser, err error) {
+	pos := r.db.Pos()
+
+	f, err := os.Open(r.db.Path())
+	if err != nil {
+		return "", 0, nil, fmt.Errorf("open database: %w", err)
+	}
+
+	pr, pw := io.Pipe()
+	go func() {
+		defer f.Close()
+		zw := lz4.NewWriter(pw)
+		_, err := io.Copy(zw, f)
+		if err == nil {
+			err = zw.Close()
+		}
+		pw.CloseWithError(err)
+	}()
+
+	return pos.Generation, pos.Index, pr, nil
+}
+
+// Snapshot takes a full, compressed snapshot of the database at its
+// current position.
+func (r *Replica) Snapshot(ctx context.Context) (SnapshotInfo, error) {
+	generation, index, rc, err := r.snapshotReader(ctx)
+	if err != nil {
+		return SnapshotInfo{}, fmt.Errorf("snapshot reader: %w", err)
+	}
+	defer rc.Close()
+
+	info, err := r.Client.WriteSnapshot(ctx, generation, index, rc)
+	if err != nil {
+		return SnapshotInfo{}, fmt.Errorf("write snapshot: %w", err)
+	}
+
+	r.Logger.Info("wrote snapshot", "pos", info.Pos())
+	return info, nil
+}
+
+// Snapshots returns every ordinary snapshot across every generation known
+// to the replica's client, ordered oldest first.
+func (r *Replica) Snapshots(ctx context.Context) ([]SnapshotInfo, error) {
+	generations, err := r.Client.Generations(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("generations: %w", err)
+	}
+
+	var infos []SnapshotInfo
+	for _, generation := range generations {
+		gi, err := r.Client.Snapshots(ctx, generation)
+		if err != nil {
+			return nil, fmt.Errorf("snapshots: %w", err)
+		}
+		infos = append(infos, gi...)
+	}
+
+	sort.Slice(infos, func(i, j int) bool { return infos[i].CreatedAt.Before(infos[j].CreatedAt) })
+	return infos, nil
+}
+
+// EnforceRetention removes ordinary snapshots older than Retention, along
+// with the now-unreferenced WAL segments that preceded them. Tagged
+// snapshots live under a separate client-side prefix and are never visited
+// here. Segments pinned by an active ReadHandle are left alone even if
+// otherwise eligible.
+func (r *Replica) EnforceRetention(ctx context.Context) error {
+	minHandleIndex, handleActive := MinActiveHandleIndex(r.db)
+
+	infos, err := r.Snapshots(ctx)
+	if err != nil {
+		return fmt.Errorf("snapshots: %w", err)
+	}
+
+	cutoff := time.Now().Add(-r.Retention)
+	expired := func(info SnapshotInfo) bool {
+		if info.CreatedAt.After(cutoff) {
+			return false
+		}
+		return !(handleActive && info.Index >= minHandleIndex)
+	}
+
+	// retainIndex is, per generation, the lowest index any snapshot
+	// surviving this pass still needs to replay through. A generation with
+	// no surviving snapshot is left out and its WAL segments untouched,
+	// since there would be no reference point left to reclaim against.
+	retainIndex := make(map[string]int)
+	for _, info := range infos {
+		if expired(info) {
+			continue
+		}
+		if idx, ok := retainIndex[info.Generation]; !ok || info.Index < idx {
+			retainIndex[info.Generation] = info.Index
+		}
+	}
+
+	for _, info := range infos {
+		if !expired(info) {
+			continue
+		}
+		if err := r.Client.DeleteSnapshot(ctx, info.Generation, info.Index); err != nil {
+			return fmt.Errorf("delete snapshot: %w", err)
+		}
+	}
+
+	for generation, keepIndex := range retainIndex {
+		if err := r.reclaimWALSegments(ctx, generation, keepIndex, minHandleIndex, handleActive); err != nil {
+			return fmt.Errorf("reclaim wal segments for generation %s: %w", generation, err)
+		}
+	}
+	return nil
+}
+
+// reclaimWALSegments deletes every L0 WAL segment in generation whose index
+// is older than keepIndex - the oldest index a snapshot surviving
+// EnforceRetention's pass still needs to replay through - leaving any
+// segment pinned by an active ReadHandle alone even if otherwise eligible.
+// It then does the same for every compaction level above L0, since a
+// compacted segment lives under its own Lx prefix that WALSegments never
+// scans and would otherwise never be reclaimed.
+func (r *Replica) reclaimWALSegments(ctx context.Context, generation string, keepIndex, minHandleIndex int, handleActive bool) error {
+	itr, err := r.Client.WALSegments(ctx, generation)
+	if err != nil {
+		return fmt.Errorf("wal segments: %w", err)
+	}
+	defer itr.Close()
+
+	var positions []Pos
+	for itr.Next() {
+		info := itr.WALSegment()
+		if info.Index >= keepIndex {
+			continue
+		}
+		if handleActive && info.Index <= minHandleIndex {
+			continue
+		}
+		positions = append(positions, info.Pos())
+	}
+	if err := itr.Close(); err != nil {
+		return err
+	}
+	if len(positions) > 0 {
+		if err := r.Client.DeleteWALSegments(ctx, positions); err != nil {
+			return err
+		}
+	}
+
+	if client, ok := r.Client.(LeveledReplicaClient); ok {
+		if err := r.reclaimCompactedWALSegments(ctx, client, generation, keepIndex, minHandleIndex, handleActive); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// reclaimCompactedWALSegments mirrors reclaimWALSegments' L0 pass across
+// every compaction level above it. Levels stop being checked once one comes
+// back with no segments at all for generation, since the compactor only
+// ever produces an Lx+1 segment by merging Lx segments, so an empty level
+// means nothing above it can exist either.
+func (r *Replica) reclaimCompactedWALSegments(ctx context.Context, client LeveledReplicaClient, generation string, keepIndex, minHandleIndex int, handleActive bool) error {
+	for level := 1; ; level++ {
+		itr, err := client.WALSegmentsAtLevel(ctx, generation, -1, level)
+		if err != nil {
+			return fmt.Errorf("wal segments at level %d: %w", level, err)
+		}
+
+		var any bool
+		offsetsByIndex := make(map[int][]int64)
+		for itr.Next() {
+			any = true
+			info := itr.WALSegment()
+			if info.Index >= keepIndex {
+				continue
+			}
+			if handleActive && info.Index <= minHandleIndex {
+				continue
+			}
+			offsetsByIndex[info.Index] = append(offsetsByIndex[info.Index], info.Offset)
+		}
+		if err := itr.Close(); err != nil {
+			return err
+		}
+		if !any {
+			return nil
+		}
+
+		for index, offsets := range offsetsByIndex {
+			if err := client.DeleteWALSegmentsAtLevel(ctx, generation, index, level, offsets); err != nil {
+				return fmt.Errorf("delete level %d segments for index %d: %w", level, index, err)
+			}
+		}
+	}
+}
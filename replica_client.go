@@ -0,0 +1,47 @@
+package litestream
+
+import (
+	"context"
+	"io"
+)
+
+// ReplicaClient reads and writes generations, snapshots and WAL segments
+// for a single replica destination (a directory, bucket, etc.). Snapshot
+// and WAL segment bytes are always lz4-compressed by the caller before
+// being handed to a client's Write* method, so implementations can store
+// and serve them verbatim.
+type ReplicaClient interface {
+	// Type returns the client type, used to identify the backend in logs
+	// (e.g. "file", "s3").
+	Type() string
+
+	// Generations returns a list of available generations.
+	Generations(ctx context.Context) ([]string, error)
+
+	// DeleteGeneration deletes all snapshots & WAL segments for a generation.
+	DeleteGeneration(ctx context.Context, generation string) error
+
+	// Snapshots returns an ordered list of available snapshots for a generation.
+	Snapshots(ctx context.Context, generation string) ([]SnapshotInfo, error)
+
+	// WriteSnapshot writes a snapshot of the database at index to the replica.
+	WriteSnapshot(ctx context.Context, generation string, index int, r io.Reader) (SnapshotInfo, error)
+
+	// SnapshotReader returns a reader for snapshot data at the given generation/index.
+	SnapshotReader(ctx context.Context, generation string, index int) (io.ReadCloser, error)
+
+	// DeleteSnapshot deletes a snapshot with the given generation & index.
+	DeleteSnapshot(ctx context.Context, generation string, index int) error
+
+	// WALSegments returns an iterator over WAL segments for a generation.
+	WALSegments(ctx context.Context, generation string) (WALSegmentIterator, error)
+
+	// WriteWALSegment writes a WAL segment starting at pos.
+	WriteWALSegment(ctx context.Context, pos Pos, r io.Reader) (WALSegmentInfo, error)
+
+	// WALSegmentReader returns a reader for the WAL segment starting at pos.
+	WALSegmentReader(ctx context.Context, pos Pos) (io.ReadCloser, error)
+
+	// DeleteWALSegments deletes WAL segments at the given positions.
+	DeleteWALSegments(ctx context.Context, a []Pos) error
+}
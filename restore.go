@@ -0,0 +1,303 @@
+package litestream
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/pierrec/lz4/v4"
+)
+
+// RestoreOptions configures Replica.Restore.
+type RestoreOptions struct {
+	// Generation restores from a specific generation. If empty, the last
+	// generation in the list Client.Generations returns is used; generation
+	// ages are not compared. Ignored if Tag is set, since the tagged
+	// snapshot determines the generation.
+	Generation string
+
+	// Tag restores up to the tagged snapshot with this name instead of the
+	// most recent ordinary snapshot. The client must implement
+	// TaggedReplicaClient.
+	Tag string
+
+	// ThroughNow replays WAL past the starting snapshot's own index,
+	// checkpointing through each subsequent index in turn, up to the
+	// newest segment recorded for the generation. Without it, Restore stops
+	// at the end of the starting snapshot's index - the only option that
+	// makes sense for Generation-based restores, but a meaningful choice
+	// for Tag-based ones, which otherwise can't recover anything written
+	// after the tag.
+	ThroughNow bool
+
+	// MaxLevel bounds how high a compaction level SelectWALSegments reads
+	// from; 0 restores from L0 (uncompacted) segments only. Ignored if the
+	// client does not implement LeveledReplicaClient.
+	MaxLevel int
+}
+
+// Restore reconstructs the database into destPath: the most recent
+// snapshot for the chosen generation (or the one named by Tag), followed
+// by every WAL byte recorded for that snapshot's index and, with
+// ThroughNow, for each later index. destPath must not already exist.
+func (r *Replica) Restore(ctx context.Context, destPath string, opts RestoreOptions) (Pos, error) {
+	snapshot, err := r.resolveRestoreSnapshot(ctx, opts)
+	if err != nil {
+		return Pos{}, fmt.Errorf("resolve snapshot: %w", err)
+	}
+
+	if err := r.restoreSnapshot(ctx, opts.Tag, snapshot, destPath); err != nil {
+		return Pos{}, fmt.Errorf("restore snapshot: %w", err)
+	}
+
+	pos, err := r.restoreWAL(ctx, snapshot, destPath, opts)
+	if err != nil {
+		return Pos{}, fmt.Errorf("restore wal: %w", err)
+	}
+
+	r.Logger.Info("restored database", "dest", destPath, "pos", pos)
+	return pos, nil
+}
+
+// resolveRestoreSnapshot determines the snapshot Restore should start from.
+func (r *Replica) resolveRestoreSnapshot(ctx context.Context, opts RestoreOptions) (SnapshotInfo, error) {
+	if opts.Tag != "" {
+		pos, err := ResolveSnapshotTag(ctx, r, opts.Tag)
+		if err != nil {
+			return SnapshotInfo{}, err
+		}
+		return SnapshotInfo{Generation: pos.Generation, Index: pos.Index}, nil
+	}
+
+	generation := opts.Generation
+	if generation == "" {
+		generations, err := r.Client.Generations(ctx)
+		if err != nil {
+			return SnapshotInfo{}, fmt.Errorf("generations: %w", err)
+		}
+		if len(generations) == 0 {
+			return SnapshotInfo{}, fmt.Errorf("no generations found")
+		}
+		generation = generations[len(generations)-1]
+	}
+
+	infos, err := r.Client.Snapshots(ctx, generation)
+	if err != nil {
+		return SnapshotInfo{}, fmt.Errorf("snapshots: %w", err)
+	}
+	if len(infos) == 0 {
+		return SnapshotInfo{}, fmt.Errorf("no snapshot found for generation %s", generation)
+	}
+	return infos[len(infos)-1], nil
+}
+
+// restoreSnapshot decompresses the snapshot for info into destPath. If tag
+// is set, info is read back via TaggedReplicaClient.TaggedSnapshotReader
+// instead of the base SnapshotReader, since tagged snapshots live under
+// their own prefix rather than alongside ordinary ones.
+func (r *Replica) restoreSnapshot(ctx context.Context, tag string, info SnapshotInfo, destPath string) error {
+	var rc io.ReadCloser
+	var err error
+	if tag != "" {
+		client, ok := r.Client.(TaggedReplicaClient)
+		if !ok {
+			return fmt.Errorf("replica client does not support tagged snapshots")
+		}
+		rc, err = client.TaggedSnapshotReader(ctx, tag, info.Generation, info.Index)
+	} else {
+		rc, err = r.Client.SnapshotReader(ctx, info.Generation, info.Index)
+	}
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+
+	f, err := os.Create(destPath)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	if _, err := io.Copy(f, lz4.NewReader(rc)); err != nil {
+		return err
+	}
+	return f.Sync()
+}
+
+// restoreWAL appends every WAL byte written during the starting snapshot's
+// index to destPath's WAL file. If opts.ThroughNow is set, it then
+// checkpoints destPath and replays each subsequent index the same way, in
+// turn, up to the newest one recorded for the generation - otherwise a
+// tagged restore could never recover anything written after the tag.
+func (r *Replica) restoreWAL(ctx context.Context, snapshot SnapshotInfo, destPath string, opts RestoreOptions) (Pos, error) {
+	pos := snapshot.Pos()
+
+	n, err := r.restoreWALIndex(ctx, snapshot.Generation, snapshot.Index, destPath, opts.MaxLevel)
+	if err != nil {
+		return Pos{}, err
+	}
+	pos.Offset += n
+
+	if !opts.ThroughNow {
+		return pos, nil
+	}
+
+	lastIndex, err := r.lastWALIndex(ctx, snapshot.Generation, opts.MaxLevel)
+	if err != nil {
+		return Pos{}, fmt.Errorf("last wal index: %w", err)
+	}
+
+	for index := snapshot.Index + 1; index <= lastIndex; index++ {
+		if err := checkpointRestored(destPath); err != nil {
+			return Pos{}, fmt.Errorf("checkpoint through index %d: %w", index-1, err)
+		}
+
+		n, err := r.restoreWALIndex(ctx, snapshot.Generation, index, destPath, opts.MaxLevel)
+		if err != nil {
+			return Pos{}, err
+		}
+		pos = Pos{Generation: snapshot.Generation, Index: index, Offset: n}
+	}
+	return pos, nil
+}
+
+// restoreWALIndex writes every WAL byte recorded for (generation, index)
+// into a fresh destPath+"-wal", preferring the highest compaction level
+// available (via SelectWALSegments) when the client implements
+// LeveledReplicaClient, so restore reads fewer, larger objects than
+// replaying raw L0 segments. It returns the number of uncompressed bytes
+// written.
+func (r *Replica) restoreWALIndex(ctx context.Context, generation string, index int, destPath string, maxLevel int) (int64, error) {
+	f, err := os.Create(destPath + "-wal")
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+
+	var n int64
+	if client, ok := r.Client.(LeveledReplicaClient); ok {
+		ranges, err := SelectWALSegments(ctx, client, generation, index, maxLevel)
+		if err != nil {
+			return 0, err
+		}
+		for _, rg := range ranges {
+			written, err := r.appendWALSegmentAtLevel(ctx, client, f, generation, rg.Level, rg.Info)
+			if err != nil {
+				return 0, err
+			}
+			n += written
+		}
+	} else {
+		itr, err := r.Client.WALSegments(ctx, generation)
+		if err != nil {
+			return 0, err
+		}
+		defer itr.Close()
+
+		for itr.Next() {
+			info := itr.WALSegment()
+			if info.Index != index {
+				continue
+			}
+			written, err := r.appendWALSegment(ctx, f, info)
+			if err != nil {
+				return 0, err
+			}
+			n += written
+		}
+		if err := itr.Close(); err != nil {
+			return 0, err
+		}
+	}
+
+	if err := f.Sync(); err != nil {
+		return 0, err
+	}
+	return n, nil
+}
+
+// lastWALIndex returns the highest WAL index with any segment recorded, at
+// any compaction level up to maxLevel, for generation - so a ThroughNow
+// restore knows how far past the starting snapshot's index to replay.
+func (r *Replica) lastWALIndex(ctx context.Context, generation string, maxLevel int) (int, error) {
+	max := -1
+	collect := func(itr WALSegmentIterator) error {
+		for itr.Next() {
+			if idx := itr.WALSegment().Index; idx > max {
+				max = idx
+			}
+		}
+		return itr.Close()
+	}
+
+	itr, err := r.Client.WALSegments(ctx, generation)
+	if err != nil {
+		return 0, err
+	}
+	if err := collect(itr); err != nil {
+		return 0, err
+	}
+
+	if client, ok := r.Client.(LeveledReplicaClient); ok {
+		for level := 1; level <= maxLevel; level++ {
+			itr, err := client.WALSegmentsAtLevel(ctx, generation, -1, level)
+			if err != nil {
+				return 0, err
+			}
+			if err := collect(itr); err != nil {
+				return 0, err
+			}
+		}
+	}
+
+	if max < 0 {
+		return 0, fmt.Errorf("no wal segments found for generation %s", generation)
+	}
+	return max, nil
+}
+
+// checkpointRestored runs a TRUNCATE checkpoint against the partially
+// restored database at destPath, merging its current WAL into the main
+// file and emptying it, the same way DB.Checkpoint does against a live
+// database, so the next index's WAL segments can be replayed from a clean
+// slate.
+func checkpointRestored(destPath string) error {
+	sqldb, err := sql.Open("sqlite3", destPath)
+	if err != nil {
+		return fmt.Errorf("open: %w", err)
+	}
+	defer sqldb.Close()
+
+	if _, err := sqldb.Exec(`PRAGMA wal_checkpoint(TRUNCATE);`); err != nil {
+		return fmt.Errorf("wal_checkpoint(TRUNCATE): %w", err)
+	}
+	return nil
+}
+
+// appendWALSegment decompresses a single L0 WAL segment and appends its raw
+// bytes to w, returning the number of uncompressed bytes written.
+func (r *Replica) appendWALSegment(ctx context.Context, w io.Writer, info WALSegmentInfo) (int64, error) {
+	rc, err := r.Client.WALSegmentReader(ctx, info.Pos())
+	if err != nil {
+		return 0, err
+	}
+	defer rc.Close()
+
+	return io.Copy(w, lz4.NewReader(rc))
+}
+
+// appendWALSegmentAtLevel decompresses a single segment at level, read
+// through client's LeveledReplicaClient capability rather than
+// ReplicaClient.WALSegmentReader, which only ever addresses L0, and appends
+// its raw bytes to w.
+func (r *Replica) appendWALSegmentAtLevel(ctx context.Context, client LeveledReplicaClient, w io.Writer, generation string, level int, info WALSegmentInfo) (int64, error) {
+	rc, err := client.WALSegmentReaderAtLevel(ctx, generation, info.Index, level, info.Offset)
+	if err != nil {
+		return 0, err
+	}
+	defer rc.Close()
+
+	return io.Copy(w, lz4.NewReader(rc))
+}
@@ -0,0 +1,18 @@
+package litestream
+
+import "time"
+
+// SnapshotInfo represents a single full-database snapshot stored by a
+// ReplicaClient. A snapshot always sits at offset zero of its index, so
+// restoring to it never requires replaying any WAL.
+type SnapshotInfo struct {
+	Generation string
+	Index      int
+	Size       int64
+	CreatedAt  time.Time
+}
+
+// Pos returns the position the snapshot restores to.
+func (info SnapshotInfo) Pos() Pos {
+	return Pos{Generation: info.Generation, Index: info.Index}
+}
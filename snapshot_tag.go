@@ -0,0 +1,91 @@
+package litestream
+
+import (
+	"context"
+	"fmt"
+	"io"
+)
+
+// TaggedReplicaClient is an optional capability that a ReplicaClient can
+// implement to support named, retention-exempt snapshots. Clients that
+// don't implement it simply can't be targeted by SnapshotWithTag; ordinary,
+// time-based snapshots continue to work as before.
+type TaggedReplicaClient interface {
+	// WriteSnapshotWithMetadata writes r as the tagged snapshot for
+	// generation, alongside the given metadata. Implementations store
+	// tagged snapshots under a prefix separate from ordinary snapshots
+	// (e.g. "snapshots-tagged/<tag>/") so EnforceRetention's time-based
+	// deletion never walks over them.
+	WriteSnapshotWithMetadata(ctx context.Context, tag, generation string, index int, meta map[string]string, r io.Reader) (SnapshotInfo, error)
+
+	// TaggedSnapshots returns every snapshot stored under tag.
+	TaggedSnapshots(ctx context.Context, tag string) ([]SnapshotInfo, error)
+
+	// TaggedSnapshotReader returns a reader for the tagged snapshot at
+	// generation/index, the tagged counterpart to
+	// ReplicaClient.SnapshotReader needed since tagged snapshots live
+	// under their own prefix rather than alongside ordinary ones.
+	TaggedSnapshotReader(ctx context.Context, tag, generation string, index int) (io.ReadCloser, error)
+
+	// DeleteTaggedSnapshot removes the tagged snapshot at generation/index.
+	DeleteTaggedSnapshot(ctx context.Context, tag, generation string, index int) error
+}
+
+// SnapshotWithTag takes a full snapshot of the database at its current
+// position, same as Snapshot, but stores it under tag with the given metadata. Tagged
+// snapshots (e.g. "pre-migration", "nightly") are excluded from
+// EnforceRetention's normal time-based GC, so they survive the rolling
+// retention window until explicitly deleted.
+func (r *Replica) SnapshotWithTag(ctx context.Context, tag string, meta map[string]string) (SnapshotInfo, error) {
+	client, ok := r.Client.(TaggedReplicaClient)
+	if !ok {
+		return SnapshotInfo{}, fmt.Errorf("replica client does not support tagged snapshots")
+	}
+
+	generation, index, rc, err := r.snapshotReader(ctx)
+	if err != nil {
+		return SnapshotInfo{}, fmt.Errorf("snapshot reader: %w", err)
+	}
+	defer rc.Close()
+
+	info, err := client.WriteSnapshotWithMetadata(ctx, tag, generation, index, meta, rc)
+	if err != nil {
+		return SnapshotInfo{}, fmt.Errorf("write tagged snapshot: %w", err)
+	}
+
+	r.Logger.Info("wrote tagged snapshot", "tag", tag, "pos", info.Pos())
+	return info, nil
+}
+
+// ListTaggedSnapshots returns every snapshot stored under tag, ordered by
+// the underlying client (oldest first, matching Snapshots).
+func (r *Replica) ListTaggedSnapshots(ctx context.Context, tag string) ([]SnapshotInfo, error) {
+	client, ok := r.Client.(TaggedReplicaClient)
+	if !ok {
+		return nil, fmt.Errorf("replica client does not support tagged snapshots")
+	}
+	return client.TaggedSnapshots(ctx, tag)
+}
+
+// DeleteTaggedSnapshot removes the tagged snapshot identified by pos.
+func (r *Replica) DeleteTaggedSnapshot(ctx context.Context, tag string, pos Pos) error {
+	client, ok := r.Client.(TaggedReplicaClient)
+	if !ok {
+		return fmt.Errorf("replica client does not support tagged snapshots")
+	}
+	return client.DeleteTaggedSnapshot(ctx, tag, pos.Generation, pos.Index)
+}
+
+// ResolveSnapshotTag returns the position of the tagged snapshot matching
+// tag, for the restore path to start from (the "-snapshot-tag" flag). If
+// multiple snapshots share the tag, the most recent one is used.
+func ResolveSnapshotTag(ctx context.Context, r *Replica, tag string) (Pos, error) {
+	infos, err := r.ListTaggedSnapshots(ctx, tag)
+	if err != nil {
+		return Pos{}, err
+	}
+	if len(infos) == 0 {
+		return Pos{}, fmt.Errorf("no snapshot found for tag %q", tag)
+	}
+	return infos[len(infos)-1].Pos(), nil
+}
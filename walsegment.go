@@ -0,0 +1,59 @@
+package litestream
+
+import "time"
+
+// WALSegmentInfo represents a single contiguous run of WAL bytes stored by
+// a ReplicaClient, starting at Offset within Index.
+type WALSegmentInfo struct {
+	Generation string
+	Index      int
+	Offset     int64
+	Size       int64
+	CreatedAt  time.Time
+}
+
+// Pos returns the position at the start of the segment.
+func (info WALSegmentInfo) Pos() Pos {
+	return Pos{Generation: info.Generation, Index: info.Index, Offset: info.Offset}
+}
+
+// WALSegmentIterator iterates over a list of WAL segments in ascending
+// order. Callers must call Close when done.
+type WALSegmentIterator interface {
+	// Next advances to the next segment, returning false once exhausted or
+	// on error; callers should check Close for the error in that case.
+	Next() bool
+
+	// WALSegment returns the segment at the iterator's current position.
+	WALSegment() WALSegmentInfo
+
+	// Close releases any resources held by the iterator and returns the
+	// first error encountered while iterating, if any.
+	Close() error
+}
+
+// WALSegmentInfoSliceIterator adapts a fixed slice of WALSegmentInfo to the
+// WALSegmentIterator interface. It is primarily useful to ReplicaClient
+// implementations whose listing is materialized up front, such as a
+// directory scan, rather than paged from a remote API.
+type WALSegmentInfoSliceIterator struct {
+	infos []WALSegmentInfo
+	i     int
+}
+
+// NewWALSegmentInfoSliceIterator returns a new iterator over infos.
+func NewWALSegmentInfoSliceIterator(infos []WALSegmentInfo) *WALSegmentInfoSliceIterator {
+	return &WALSegmentInfoSliceIterator{infos: infos, i: -1}
+}
+
+func (itr *WALSegmentInfoSliceIterator) Next() bool {
+	if itr.i+1 >= len(itr.infos) {
+		return false
+	}
+	itr.i++
+	return true
+}
+
+func (itr *WALSegmentInfoSliceIterator) WALSegment() WALSegmentInfo { return itr.infos[itr.i] }
+
+func (itr *WALSegmentInfoSliceIterator) Close() error { return nil }